@@ -3,6 +3,19 @@
 // For production build served by Go: VITE_API_URL=/api (or leave empty)
 const API_BASE = import.meta.env.VITE_API_URL || '/api';
 
+// Latest ETag seen per document id, sent back as If-Match on updates
+const documentEtags = new Map();
+
+const rememberEtag = (id) => (res) => {
+  const etag = res.headers.get('etag');
+  if (etag) documentEtags.set(String(id), etag);
+};
+
+const ifMatch = (id) => {
+  const etag = documentEtags.get(String(id));
+  return etag ? { 'If-Match': etag } : {};
+};
+
 const getToken = () => {
   try {
     return localStorage.getItem('token');
@@ -11,7 +24,7 @@ const getToken = () => {
   }
 };
 
-async function request(path, { method = 'GET', body, headers } = {}) {
+async function request(path, { method = 'GET', body, headers, onResponse } = {}) {
   const url = `${API_BASE}${path}`;
   console.log(`[API] ${method} ${url}`); // Debug logging
 
@@ -36,6 +49,8 @@ async function request(path, { method = 'GET', body, headers } = {}) {
     throw err;
   }
 
+  if (onResponse) onResponse(res);
+
   const ct = res.headers.get('content-type') || '';
   return ct.includes('application/json') ? res.json() : res.text();
 }
@@ -61,7 +76,7 @@ export const api = {
   },
   
   getDocument: async (id) => {
-    const res = await request(`/document?id=${encodeURIComponent(id)}`);
+    const res = await request(`/document?id=${encodeURIComponent(id)}`, { onResponse: rememberEtag(id) });
     return Array.isArray(res) ? res[0] : res;
   },
   
@@ -81,12 +96,25 @@ export const api = {
     
     return request(`/document?${searchParams.toString()}`, { 
       method: 'PUT', 
-      body: payload 
+      body: payload,
+      headers: ifMatch(id),
+      onResponse: rememberEtag(id),
     });
   },
   
-  deleteDocument: (id) =>
-    request(`/document?id=${encodeURIComponent(id)}`, { method: 'DELETE' }),
+  // Deletes need If-Match too; lists carry no ETag, so fetch one if none is known
+  deleteDocument: async (id) => {
+    if (!documentEtags.has(String(id))) {
+      await api.getDocument(id);
+    }
+    return request(`/document?id=${encodeURIComponent(id)}`, {
+      method: 'DELETE',
+      headers: ifMatch(id),
+    });
+  },
+
+  undeleteDocument: (id) =>
+    request(`/document/undelete?id=${encodeURIComponent(id)}`, { method: 'POST' }),
 
   // Artifact APIs
   uploadArtifact: (file) => {
@@ -108,6 +136,29 @@ export const api = {
       throw err;
     }),
 
+  listArtifacts: (params = {}) => {
+    const searchParams = new URLSearchParams(params);
+    const query = searchParams.toString();
+    return request(query ? `/artifacts?${query}` : '/artifacts');
+  },
+
+  listDocumentArtifacts: (id) =>
+    request(`/document/artifacts?id=${encodeURIComponent(id)}`, { onResponse: rememberEtag(id) }),
+
+  attachArtifact: (id, artifactId) =>
+    request(`/document/artifacts?id=${encodeURIComponent(id)}`, {
+      method: 'POST',
+      body: { artifact_id: artifactId },
+      headers: ifMatch(id),
+      onResponse: rememberEtag(id),
+    }),
+
+  detachArtifact: (id, artifactId) =>
+    request(
+      `/document/artifacts?id=${encodeURIComponent(id)}&artifact_id=${encodeURIComponent(artifactId)}`,
+      { method: 'DELETE', headers: ifMatch(id), onResponse: rememberEtag(id) },
+    ),
+
   // Document version APIs
   listDocumentVersions: (id) =>
     request(`/document/versions?id=${encodeURIComponent(id)}`),
@@ -117,7 +168,9 @@ export const api = {
   
   restoreDocumentVersion: (id, versionNumber) =>
     request(`/document/restore?id=${encodeURIComponent(id)}&version=${encodeURIComponent(versionNumber)}`, { 
-      method: 'POST' 
+      method: 'POST',
+      headers: ifMatch(id),
+      onResponse: rememberEtag(id),
     }),
 
   // Document partial data APIs
@@ -155,6 +208,16 @@ export const api = {
     request(`/script-request?id=${encodeURIComponent(id)}`, { 
       method: 'DELETE' 
     }),
+
+  undeleteScriptRequest: (id) =>
+    request(`/script-request/undelete?id=${encodeURIComponent(id)}`, { method: 'POST' }),
+
+  // Trash APIs
+  listTrash: (type) =>
+    request(type ? `/trash?type=${encodeURIComponent(type)}` : '/trash'),
+
+  purgeTrashEntry: (type, id) =>
+    request(`/trash?type=${encodeURIComponent(type)}&id=${encodeURIComponent(id)}`, { method: 'DELETE' }),
 };
 
 // Export for debugging
@@ -2,7 +2,7 @@ import { useNavigate, useBeforeUnload } from "react-router-dom";
 import { useCallback, useEffect, useMemo, useRef, useState } from "react";
 import { useStore } from "../store";
 import { useToast } from "../components/Toast";
-import { buildScriptFromForm } from "../utils/scriptFormat";
+import { buildScriptFromForm, temperatureUnitName } from "../utils/scriptFormat";
 import hpiDiagram from "../assets/hpi-diagram.png";
 
 const ratingOptions = [
@@ -720,10 +720,10 @@ const RequestNew = () => {
                   </label>
                   <label className="block space-y-1">
                     <span className="text-sm text-gray-700">Temperature Unit</span>
-                    <select className={`${inputClass} pr-8`} value={getField(["patient", "vitals", "temp", "unit"]) ?? ""} onChange={(e) => setNumberField(["patient", "vitals", "temp", "unit"], e.target.value)}>
+                    <select className={`${inputClass} pr-8`} value={temperatureUnitName(getField(["patient", "vitals", "temp", "unit"]))} onChange={(e) => setField(["patient", "vitals", "temp", "unit"], e.target.value)}>
                       <option value="">Select unit</option>
-                      <option value="0">Celcius</option>
-                      <option value="1">Fahrenheit</option>
+                      <option value="Celsius">Celsius</option>
+                      <option value="Fahrenheit">Fahrenheit</option>
                     </select>
                   </label>
                 </div>
@@ -1573,7 +1573,7 @@ const RequestNew = () => {
                     <li>{(getField(["patient", "vitals", "respirations"]) ?? "") !== "" ? getField(["patient", "vitals", "respirations"]) : "-"} breaths/min</li>
                     <li>{(getField(["patient", "vitals", "pressure", "top"]) ?? "") !== "" ? getField(["patient", "vitals", "pressure", "top"]) : "-"}/{(getField(["patient", "vitals", "pressure", "bottom"]) ?? "") !== "" ? getField(["patient", "vitals", "pressure", "bottom"]) : "-"} mmHg</li>
                     <li>{(getField(["patient", "vitals", "blood_oxygen"]) ?? "") !== "" ? getField(["patient", "vitals", "blood_oxygen"]) : "-"}%</li>
-                    <li>{(getField(["patient", "vitals", "temp", "reading"]) ?? "") !== "" ? getField(["patient", "vitals", "temp", "reading"]) : "-"} {String(getField(["patient", "vitals", "temp", "unit"]) || "") === "1" ? "Fahrenheit" : "Celsius"}</li>
+                    <li>{(getField(["patient", "vitals", "temp", "reading"]) ?? "") !== "" ? getField(["patient", "vitals", "temp", "reading"]) : "-"} {temperatureUnitName(getField(["patient", "vitals", "temp", "unit"])) || "Celsius"}</li>
                   </ul>
                 </div>
               </div>
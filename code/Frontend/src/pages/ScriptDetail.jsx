@@ -27,6 +27,7 @@ const initialForm = {
   },
   patient: {
     name: "",
+    age: "",
     visit_reason: "",
     context: "",
     task: "",
@@ -37,6 +38,9 @@ const initialForm = {
       pressure: { top: "", bottom: "" },
       blood_oxygen: "",
       temp: { reading: "", unit: "" },
+      weight: { reading: "", unit: "" },
+      height: { reading: "", unit: "" },
+      glucose: { reading: "", unit: "" },
     },
   },
   sp: {
@@ -215,12 +219,16 @@ function requestToScript(req = {}) {
     },
     patient: {
       name: req.patient_demog || "Patient",
+      age: "",
       vitals: {
         heart_rate: "",
         respirations: "",
         pressure: { top: "", bottom: "" },
         blood_oxygen: "",
         temp: { reading: "", unit: "" },
+        weight: { reading: "", unit: "" },
+        height: { reading: "", unit: "" },
+        glucose: { reading: "", unit: "" },
       },
       visit_reason: reasonForVisit,
       context: req.case_setting || "",
@@ -290,6 +298,7 @@ const fieldSections = [
     title: "Patient",
     fields: [
       { label: "Name", path: ["patient", "name"] },
+      { label: "Age", path: ["patient", "age"], type: "number" },
       { label: "Visit Reason", path: ["patient", "visit_reason"] },
       { label: "Context", path: ["patient", "context"] },
       { label: "Task", path: ["patient", "task"] },
@@ -307,8 +316,26 @@ const fieldSections = [
       { label: "Temperature Reading", path: ["patient", "vitals", "temp", "reading"], type: "number" },
       { label: "Temperature Unit", path: ["patient", "vitals", "temp", "unit"], type: "select", options: [
         { label: "Select unit", value: "" },
-        { label: "Celsius", value: "C" },
-        { label: "Fahrenheit", value: "F" },
+        { label: "Celsius", value: "Celsius" },
+        { label: "Fahrenheit", value: "Fahrenheit" },
+      ] },
+      { label: "Weight", path: ["patient", "vitals", "weight", "reading"], type: "number" },
+      { label: "Weight Unit", path: ["patient", "vitals", "weight", "unit"], type: "select", options: [
+        { label: "Select unit", value: "" },
+        { label: "kg", value: "Kilograms" },
+        { label: "lb", value: "Pounds" },
+      ] },
+      { label: "Height", path: ["patient", "vitals", "height", "reading"], type: "number" },
+      { label: "Height Unit", path: ["patient", "vitals", "height", "unit"], type: "select", options: [
+        { label: "Select unit", value: "" },
+        { label: "cm", value: "Centimeters" },
+        { label: "in", value: "Inches" },
+      ] },
+      { label: "Blood Glucose", path: ["patient", "vitals", "glucose", "reading"], type: "number" },
+      { label: "Glucose Unit", path: ["patient", "vitals", "glucose", "unit"], type: "select", options: [
+        { label: "Select unit", value: "" },
+        { label: "mg/dL", value: "mg/dL" },
+        { label: "mmol/L", value: "mmol/L" },
       ] },
     ],
   },
@@ -487,11 +514,14 @@ const ScriptDetail = () => {
   const fieldRefs = useRef({});
   const [highlightPath, setHighlightPath] = useState(null);
 
+  // Always load the script itself, even when the library already listed it: the
+  // response carries the ETag that edits and restores must send back as If-Match
   useEffect(() => {
-    if (!item && requestsLoaded && typeof store.fetchById === "function" && !requestFallback) {
+    if (requestsLoaded && typeof store.fetchById === "function" && !requestFallback) {
       store.fetchById(id);
     }
-  }, [id, item, store, requestFallback, requestsLoaded]);
+    // eslint-disable-next-line react-hooks/exhaustive-deps
+  }, [id, requestFallback, requestsLoaded]);
 
   useEffect(() => {
     if (activeItem?.versions?.length) {
@@ -829,6 +859,7 @@ const ScriptDetail = () => {
             <div className="font-semibold text-gray-900">Patient Snapshot</div>
             <ul className="text-sm text-gray-700 space-y-1">
               <li><span className="font-semibold">Patient:</span> {data?.patient?.name || empty}</li>
+              <li><span className="font-semibold">Age:</span> {data?.patient?.age ?? empty}</li>
               <li><span className="font-semibold">Visit Reason:</span> {data?.patient?.visit_reason || data?.admin?.reson_for_visit || empty}</li>
               <li><span className="font-semibold">Context:</span> {data?.patient?.context || empty}</li>
               <li><span className="font-semibold">Task:</span> {data?.patient?.task || empty}</li>
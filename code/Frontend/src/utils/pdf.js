@@ -288,9 +288,10 @@ function buildScriptPdfDoc(item, versionObj) {
   const formatTemperaturePair = (reading, unitRaw) => {
     const n = Number(reading);
     if (!Number.isFinite(n) || n <= 0) return "";
-    const unit = Number(unitRaw);
-    const c = unit === 1 ? (n - 32) * (5 / 9) : n;
-    const f = unit === 1 ? n : (n * (9 / 5) + 32);
+    const unitText = String(unitRaw ?? "").toLowerCase();
+    const isFahrenheit = unitText === "1" || unitText.startsWith("f");
+    const c = isFahrenheit ? (n - 32) * (5 / 9) : n;
+    const f = isFahrenheit ? n : (n * (9 / 5) + 32);
     const toOne = (value) => Number(value.toFixed(1)).toString();
     return `${toOne(c)} C / ${toOne(f)} F`;
   };
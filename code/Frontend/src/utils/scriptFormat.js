@@ -224,12 +224,15 @@ const normalizeDiagramMarkers = (value) => {
     .filter(Boolean);
 };
 
+// Temperature unit name as the API stores it; older scripts use 0/1 and "C"/"F"
+export function temperatureUnitName(unit) {
+  const text = String(unit ?? '').trim().toLowerCase();
+  if (text === '') return '';
+  return text === '1' || text.startsWith('f') ? 'Fahrenheit' : 'Celsius';
+}
+
 export function buildScriptFromForm(f = {}) {
-  //  temperature unit to numeric
-  const unitRaw = f.patient?.vitals?.temp?.unit;
-  const tempUnit = typeof unitRaw === 'number'
-    ? unitRaw
-    : String(unitRaw || 'Celcius').toLowerCase().startsWith('f') ? 1 : 0;
+  const tempUnit = temperatureUnitName(f.patient?.vitals?.temp?.unit) || 'Celsius';
 
   const medicationsArr = normalizeMedicationEntries(f.med_hist?.medications);
   const nonPrescriptionMeds = normalizeNonPrescriptionEntries(f.med_hist?.non_prescription_medications);
@@ -261,6 +264,8 @@ export function buildScriptFromForm(f = {}) {
     patient: {
       name: f.patient?.name || 'Unknown',
       date_of_birth: f.patient?.date_of_birth || '',
+      // Leave a blank age unset; 0 means an infant, not an unknown age
+      age: f.patient?.age === '' || f.patient?.age == null ? null : Number(f.patient.age),
       vitals: {
         heart_rate: Number(f.patient?.vitals?.heart_rate || 0),
         respirations: Number(f.patient?.vitals?.respirations || 0),
@@ -273,7 +278,21 @@ export function buildScriptFromForm(f = {}) {
           reading: Number(f.patient?.vitals?.temp?.reading || 0),
           unit: tempUnit,
         },
+        weight: {
+          reading: Number(f.patient?.vitals?.weight?.reading || 0),
+          unit: f.patient?.vitals?.weight?.unit || 'Kilograms',
+        },
+        height: {
+          reading: Number(f.patient?.vitals?.height?.reading || 0),
+          unit: f.patient?.vitals?.height?.unit || 'Centimeters',
+        },
+        glucose: {
+          reading: Number(f.patient?.vitals?.glucose?.reading || 0),
+          unit: f.patient?.vitals?.glucose?.unit || 'mg/dL',
+        },
+        ...(f.patient?.vitals?.orthostatic ? { orthostatic: f.patient.vitals.orthostatic } : {}),
       },
+      ...(Array.isArray(f.patient?.vitals_timeline) ? { vitals_timeline: f.patient.vitals_timeline } : {}),
       visit_reason: f.patient?.visit_reason || f.admin?.reson_for_visit || '',
       context: f.patient?.context || '',
       task: f.patient?.task || '',
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"net/http"
+
+	"VCCwebsite/internal/oAuth"
+)
+
+// actorPIIRoles may see actor contact details, employee IDs and payroll time codes.
+// Everyone else only gets what they need to cast an actor.
+var actorPIIRoles = []oAuth.Role{oAuth.RoleAdmin, oAuth.RoleSPCoordinator}
+
+// CastingActor is the view of an Actor returned to roles without PII access
+type CastingActor struct {
+	ID          int64   `json:"id"`
+	Name        string  `json:"name"`
+	Notes       *string `json:"notes,omitempty"`
+	AgeRange    *string `json:"age_range,omitempty"`
+	Pronouns    *string `json:"pronouns,omitempty"`
+	WorkdayName string  `json:"workday_name"`
+	CreatedAt   string  `json:"created_at"`
+	UpdatedAt   string  `json:"updated_at"`
+}
+
+// canViewActorPII reports whether the caller may see every actor column
+func canViewActorPII(r *http.Request) bool {
+	return callerHasAnyRole(r, actorPIIRoles...)
+}
+
+// actorView returns the representation of a that the caller is allowed to see
+func actorView(r *http.Request, a Actor) any {
+	if canViewActorPII(r) {
+		return a
+	}
+	return CastingActor{
+		ID:          a.ID,
+		Name:        a.Name,
+		Notes:       a.Notes,
+		AgeRange:    a.AgeRange,
+		Pronouns:    a.Pronouns,
+		WorkdayName: a.WorkdayName,
+		CreatedAt:   a.CreatedAt,
+		UpdatedAt:   a.UpdatedAt,
+	}
+}
+
+// actorListView applies actorView to every actor in the list
+func actorListView(r *http.Request, actors []Actor) any {
+	if canViewActorPII(r) {
+		return actors
+	}
+	views := make([]any, 0, len(actors))
+	for _, a := range actors {
+		views = append(views, actorView(r, a))
+	}
+	return views
+}
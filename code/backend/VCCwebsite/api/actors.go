@@ -69,11 +69,23 @@ func (h *ActorHandler) handleByID(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// actorSortFields are the sort= names accepted by GET /api/actors. Contact details are
+// left out so the order cannot reveal them to roles that may not see them.
+var actorSortFields = map[string]string{
+	"name":       "name",
+	"age_range":  "age_range",
+	"pronouns":   "pronouns",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+}
+
 // get
 // Supports optional query parameters (all ANDed together):
 //   ?name=alice        → case-insensitive partial match on name
 //   ?pronouns=she/her  → exact match on pronouns
 //   ?age_range=20s     → exact match on age_range
+//   ?page=2&limit=25   → paging, with the match count in X-Total-Count
+//   ?sort=-updated_at  → sort on name, age_range, pronouns, created_at, updated_at
 
 func (h *ActorHandler) listActors(w http.ResponseWriter, r *http.Request) {
 	q := r.URL.Query()
@@ -81,11 +93,18 @@ func (h *ActorHandler) listActors(w http.ResponseWriter, r *http.Request) {
 	pronouns := strings.TrimSpace(q.Get("pronouns"))
 	ageRange := strings.TrimSpace(q.Get("age_range"))
 
+	params, err := parseListParams(r, actorSortFields, sortKey{Field: "name"})
+	if err != nil {
+		actorWriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	query := `
 		SELECT id, name, email, notes, phone_number, age_range, pronouns,
 		       employee_id, workday_name, time_code, lead_time_code,
 		       specialized_time_code, created_at, updated_at
 		FROM actors`
+	countQuery := `SELECT COUNT(*) FROM actors`
 
 	var conditions []string
 	var args []any
@@ -104,9 +123,22 @@ func (h *ActorHandler) listActors(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if len(conditions) > 0 {
-		query += " WHERE " + strings.Join(conditions, " AND ")
+		where := " WHERE " + strings.Join(conditions, " AND ")
+		query += where
+		countQuery += where
+	}
+
+	var total int64
+	if err := h.db.QueryRowContext(r.Context(), countQuery, args...).Scan(&total); err != nil {
+		actorInternalError(w, err)
+		return
+	}
+
+	query += params.orderBy("id")
+	if params.Limit > 0 {
+		query += " LIMIT ? OFFSET ?"
+		args = append(args, params.Limit, params.Offset())
 	}
-	query += " ORDER BY name"
 
 	rows, err := h.db.QueryContext(r.Context(), query, args...)
 	if err != nil {
@@ -129,7 +161,8 @@ func (h *ActorHandler) listActors(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	actorWriteJSON(w, http.StatusOK, actors)
+	setListHeaders(w, params, total)
+	actorWriteJSON(w, http.StatusOK, actorListView(r, actors))
 }
 
 // get
@@ -154,7 +187,7 @@ func (h *ActorHandler) getActor(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	actorWriteJSON(w, http.StatusOK, a)
+	actorWriteJSON(w, http.StatusOK, actorView(r, a))
 }
 
 // post
@@ -3,26 +3,25 @@ package api
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"path/filepath"
 	"strings"
 	"time"
 
-	scripts "VCCwebsite/internal/model"
+	"VCCwebsite/internal/artifactstore"
+	"VCCwebsite/internal/oAuth"
 
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/gridfs"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-const (
-	maxArtifactSize = 5 * 1024 * 1024 // 5MB
-	artifactBucket  = "artifacts"
-)
+const maxArtifactSize = 5 * 1024 * 1024 // 5MB
 
 var allowedArtifactTypes = map[string]bool{
 	"application/pdf": true,
@@ -30,36 +29,45 @@ var allowedArtifactTypes = map[string]bool{
 	"image/jpeg":      true,
 }
 
-func ArtifactHandler(client *mongo.Client) http.HandlerFunc {
+// ArtifactHandler uploads, lists, downloads and deletes artifacts in the configured
+// store. The ID is taken from ?id= or the path (/api/artifacts/{id}); a GET without
+// one lists the stored artifacts.
+// Uploads and downloads work without MongoDB when the store does not live there;
+// deletes need it to check that nothing still refers to the artifact.
+func ArtifactHandler(client *mongo.Client, store artifactstore.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 
-		if client == nil {
-			respondWithError(w, http.StatusServiceUnavailable, "Database connection not available")
-			return
-		}
-
-		db := client.Database("vccwebsite")
-		bucket, err := gridfs.NewBucket(db, options.GridFSBucket().SetName(artifactBucket))
-		if err != nil {
-			respondWithError(w, http.StatusInternalServerError, "Unable to initialize artifact storage")
+		if store == nil {
+			respondWithError(w, http.StatusServiceUnavailable, "Artifact storage not available")
 			return
 		}
 
 		switch r.Method {
 		case http.MethodPost:
-			handleArtifactUpload(w, r, bucket)
+			handleArtifactUpload(w, r, client, store)
 		case http.MethodGet:
-			handleArtifactDownload(w, r, bucket)
+			if artifactIDFromRequest(r) == "" {
+				handleListArtifacts(w, r, client, store)
+				return
+			}
+			handleArtifactDownload(w, r, store)
 		case http.MethodDelete:
-			handleArtifactDelete(w, r, bucket)
+			if client == nil {
+				respondWithError(w, http.StatusServiceUnavailable, "Database connection not available")
+				return
+			}
+			handleArtifactDelete(w, r, client.Database("vccwebsite"), store)
 		default:
 			respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
 		}
 	}
 }
 
-func handleArtifactUpload(w http.ResponseWriter, r *http.Request, bucket *gridfs.Bucket) {
+// handleArtifactUpload stores an uploaded file. With MongoDB available, a file whose
+// content is already stored is not stored again: the existing artifact is claimed for
+// the caller and returned as stored, with 200 instead of 201.
+func handleArtifactUpload(w http.ResponseWriter, r *http.Request, client *mongo.Client, store artifactstore.Store) {
 	// allow a bit of overhead for multipart boundaries
 	r.Body = http.MaxBytesReader(w, r.Body, maxArtifactSize+1024*1024)
 	if err := r.ParseMultipartForm(maxArtifactSize + 1024*1024); err != nil {
@@ -87,54 +95,99 @@ func handleArtifactUpload(w http.ResponseWriter, r *http.Request, bucket *gridfs
 		return
 	}
 
-	reader := io.MultiReader(bytes.NewReader(buffer[:n]), file)
-	uploadedAt := time.Now().UTC().Format(time.RFC3339)
-
-	uploadOpts := options.GridFSUpload().SetMetadata(bson.M{
-		"content_type": detected,
-		"size":         header.Size,
-		"uploaded_at":  uploadedAt,
-	})
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
 
-	uploadID, err := bucket.UploadFromStream(header.Filename, reader, uploadOpts)
+	hash := sha256.New()
+	body, err := io.ReadAll(io.TeeReader(io.MultiReader(bytes.NewReader(buffer[:n]), file), hash))
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to store artifact")
+		respondWithError(w, http.StatusBadRequest, "Invalid upload payload")
 		return
 	}
-	artifactID := uploadID.Hex()
+	digest := hex.EncodeToString(hash.Sum(nil))
 
-	artifact := scripts.Artifact{
-		ID:          artifactID,
+	caller := callerIdentity(r)
+	var database *mongo.Database
+	if client != nil {
+		database = client.Database("vccwebsite")
+		if respondWithExistingArtifact(ctx, w, database, store, digest, caller) {
+			return
+		}
+	}
+
+	info, err := store.Put(ctx, artifactstore.Info{
 		Name:        header.Filename,
 		ContentType: detected,
-		Size:        header.Size,
-		UploadedAt:  uploadedAt,
-		URL:         fmt.Sprintf("/api/artifact?id=%s", artifactID),
+		Size:        int64(len(body)),
+		UploadedBy:  caller,
+	}, bytes.NewReader(body))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Failed to store artifact")
+		return
+	}
+
+	storeThumbnails(ctx, store, info, body)
+
+	if database != nil {
+		err := registerArtifactBlob(ctx, database, info)
+		if mongo.IsDuplicateKeyError(err) {
+			// An identical upload finished first; keep its copy and drop ours
+			if err := store.Delete(ctx, info.ID); err != nil {
+				log.Printf("Removing duplicate artifact %s failed: %v", info.ID, err)
+			}
+			if respondWithExistingArtifact(ctx, w, database, store, digest, caller) {
+				return
+			}
+			respondWithError(w, http.StatusConflict, "Identical upload in progress, please retry")
+			return
+		}
+		if err != nil {
+			// Stored fine, it just will not be found by later identical uploads
+			log.Printf("Registering artifact %s digest failed: %v", info.ID, err)
+		}
 	}
 
-	respondWithJSON(w, http.StatusCreated, artifact)
+	respondWithJSON(w, http.StatusCreated, artifactFromInfo(info))
 }
 
-func handleArtifactDownload(w http.ResponseWriter, r *http.Request, bucket *gridfs.Bucket) {
-	artifactID := r.URL.Query().Get("id")
+// respondWithExistingArtifact answers an upload with the stored artifact of the same
+// content, if there is one. It reports whether a response was written.
+func respondWithExistingArtifact(ctx context.Context, w http.ResponseWriter, database *mongo.Database, store artifactstore.Store, digest, caller string) bool {
+	info, found, err := claimArtifactByDigest(ctx, database, store, digest, caller)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error checking for an identical artifact")
+		return true
+	}
+	if !found {
+		return false
+	}
+	respondWithJSON(w, http.StatusOK, artifactFromInfo(info))
+	return true
+}
+
+// handleArtifactDownload serves an artifact with http.ServeContent, so Range requests,
+// If-None-Match / If-Modified-Since (304) and If-Range all work. Artifacts never change
+// once stored, which makes the content digest (or ID and upload time) a strong ETag.
+// GET /api/artifact?id=xxx
+// GET /api/artifact?id=xxx&inline=true - display in the browser instead of downloading
+func handleArtifactDownload(w http.ResponseWriter, r *http.Request, store artifactstore.Store) {
+	artifactID := artifactIDFromRequest(r)
 	if artifactID == "" {
 		respondWithError(w, http.StatusBadRequest, "Artifact ID is required")
 		return
 	}
 
-	objectID, err := primitive.ObjectIDFromHex(artifactID)
-	if err != nil {
+	if !primitive.IsValidObjectID(artifactID) {
 		respondWithError(w, http.StatusBadRequest, "Invalid artifact ID format")
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	var fileDoc bson.M
-	err = bucket.GetFilesCollection().FindOne(ctx, bson.M{"_id": objectID}).Decode(&fileDoc)
+	info, err := store.Stat(ctx, artifactID)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
+		if err == artifactstore.ErrNotFound {
 			respondWithError(w, http.StatusNotFound, "Artifact not found")
 			return
 		}
@@ -142,33 +195,132 @@ func handleArtifactDownload(w http.ResponseWriter, r *http.Request, bucket *grid
 		return
 	}
 
-	filename := getString(fileDoc["filename"], "artifact")
-	contentType := ""
-	if meta, ok := fileDoc["metadata"].(bson.M); ok {
-		contentType = getString(meta["content_type"], "")
-		if contentType == "" {
-			contentType = getString(meta["contentType"], "")
-		}
+	filename := info.Name
+	if filename == "" {
+		filename = "artifact"
 	}
+	contentType := info.ContentType
 	if contentType == "" {
 		contentType = "application/octet-stream"
 	}
 
-	if length, ok := fileDoc["length"].(int64); ok && length > 0 {
-		w.Header().Set("Content-Length", fmt.Sprintf("%d", length))
+	// Only the upload whitelist is rendered inline; anything else that slipped in on
+	// its file extension is still downloaded
+	disposition := "attachment"
+	if r.URL.Query().Get("inline") == "true" && allowedArtifactTypes[contentType] {
+		disposition = "inline"
 	}
 
 	w.Header().Set("Content-Type", contentType)
-	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", sanitizeFilename(filename)))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("%s; filename=\"%s\"", disposition, sanitizeFilename(filename)))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("ETag", artifactETag(info))
+	w.Header().Set("Cache-Control", "private, no-cache") // always revalidate, usually a 304
 
-	if _, err := bucket.DownloadToStream(objectID, w); err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to stream artifact")
-		return
+	content := &artifactContent{ctx: ctx, store: store, id: info.ID, size: info.Size}
+	defer content.Close()
+
+	http.ServeContent(w, r, filename, info.UploadedAt, content)
+	if content.err != nil {
+		log.Printf("Streaming artifact %s failed: %v", info.ID, content.err)
+	}
+}
+
+// artifactETag is the strong validator of an artifact
+func artifactETag(info artifactstore.Info) string {
+	switch {
+	case info.SHA256 != "":
+		return `"sha256-` + info.SHA256 + `"`
+	case info.MD5 != "":
+		return `"md5-` + info.MD5 + `"`
+	default:
+		return fmt.Sprintf(`"%s-%d"`, info.ID, info.UploadedAt.Unix())
 	}
 }
 
-func handleArtifactDelete(w http.ResponseWriter, r *http.Request, bucket *gridfs.Bucket) {
-	artifactID := r.URL.Query().Get("id")
+// artifactContent is the io.ReadSeeker http.ServeContent needs, on top of a store that
+// only streams from the start. The artifact is opened on the first Read, so answering
+// a 304 or 416 never touches the content; seeking forward skips bytes and seeking
+// back (multi-range requests) reopens it.
+type artifactContent struct {
+	ctx    context.Context
+	store  artifactstore.Store
+	id     string
+	size   int64
+	offset int64 // position requested by Seek
+
+	stream   io.ReadCloser
+	position int64 // position of stream
+	err      error // first failure, for logging once the response is gone
+}
+
+func (c *artifactContent) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += c.offset
+	case io.SeekEnd:
+		offset += c.size
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+	c.offset = offset
+	return offset, nil
+}
+
+func (c *artifactContent) Read(p []byte) (int, error) {
+	if c.offset >= c.size {
+		return 0, io.EOF
+	}
+	if c.stream == nil || c.offset < c.position {
+		if err := c.reopen(); err != nil {
+			c.err = err
+			return 0, err
+		}
+	}
+	if skip := c.offset - c.position; skip > 0 {
+		skipped, err := io.CopyN(io.Discard, c.stream, skip)
+		c.position += skipped
+		if err != nil {
+			c.err = err
+			return 0, err
+		}
+	}
+
+	n, err := c.stream.Read(p)
+	c.position += int64(n)
+	c.offset = c.position
+	if err != nil && err != io.EOF {
+		c.err = err
+	}
+	return n, err
+}
+
+func (c *artifactContent) reopen() error {
+	c.Close()
+	stream, _, err := c.store.Open(c.ctx, c.id)
+	if err != nil {
+		return err
+	}
+	c.stream = stream
+	c.position = 0
+	return nil
+}
+
+func (c *artifactContent) Close() error {
+	if c.stream == nil {
+		return nil
+	}
+	err := c.stream.Close()
+	c.stream = nil
+	return err
+}
+
+func handleArtifactDelete(w http.ResponseWriter, r *http.Request, database *mongo.Database, store artifactstore.Store) {
+	artifactID := artifactIDFromRequest(r)
 	if artifactID == "" {
 		respondWithError(w, http.StatusBadRequest, "Artifact ID is required")
 		return
@@ -180,7 +332,59 @@ func handleArtifactDelete(w http.ResponseWriter, r *http.Request, bucket *gridfs
 		return
 	}
 
-	if err := bucket.Delete(objectID); err != nil {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Other callers uploaded the same file; only give up the caller's own claim.
+	// Admins may delete a file only others claim, unless it is claimed again meanwhile.
+	started := time.Now().UTC()
+	remaining, held, err := releaseArtifactClaim(ctx, database, artifactID, callerIdentity(r))
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error releasing artifact")
+		return
+	}
+	unclaimed := unclaimedArtifact
+	if len(remaining) > 0 {
+		switch {
+		case held:
+			respondWithJSON(w, http.StatusOK, map[string]interface{}{
+				"message": "Artifact claim released",
+				"claims":  len(remaining),
+			})
+			return
+		case !callerHasAnyRole(r, oAuth.RoleAdmin):
+			respondWithJSON(w, http.StatusConflict, map[string]interface{}{
+				"error":  "Artifact is claimed by other uploads",
+				"claims": len(remaining),
+			})
+			return
+		}
+		unclaimed = claimedBefore(started)
+	}
+
+	// Scripts and requests still showing the artifact keep it alive
+	refs, err := findReferences(ctx, database, "artifact", objectID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error checking artifact references")
+		return
+	}
+	if blocking := blockingReferences(refs); len(blocking) > 0 {
+		respondWithJSON(w, http.StatusConflict, map[string]interface{}{
+			"error":      "Artifact is still referenced",
+			"references": blocking,
+		})
+		return
+	}
+
+	if err := removeArtifact(ctx, database, store, artifactID, unclaimed); err != nil {
+		if err == artifactstore.ErrNotFound {
+			respondWithError(w, http.StatusNotFound, "Artifact not found")
+			return
+		}
+		if err == errArtifactClaimed {
+			respondWithError(w, http.StatusConflict, "Artifact was just uploaded again, please retry")
+			return
+		}
 		respondWithError(w, http.StatusInternalServerError, "Error deleting artifact")
 		return
 	}
@@ -213,15 +417,3 @@ func sanitizeFilename(name string) string {
 	}
 	return clean
 }
-
-func getString(value interface{}, fallback string) string {
-	if value == nil {
-		return fallback
-	}
-	switch v := value.(type) {
-	case string:
-		return v
-	default:
-		return fmt.Sprint(v)
-	}
-}
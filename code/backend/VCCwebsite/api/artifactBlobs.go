@@ -0,0 +1,250 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"VCCwebsite/internal/artifactstore"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// artifactBlobsCollection catalogues the stored artifacts: GET /api/artifacts is
+// answered from it rather than by walking the store, and its content digests let an
+// upload of a file that is already stored return the existing artifact instead of a
+// copy. The sweeper's walk of the store adds entries missing from it.
+const artifactBlobsCollection = "artifact_blobs"
+
+// errArtifactClaimed reports an artifact that an upload claimed while it was being
+// removed; it is kept
+var errArtifactClaimed = errors.New("artifact is claimed by an upload")
+
+// artifactBlob is the entry of one stored artifact in artifact_blobs, with the
+// metadata of the store so listings need not ask the store for it.
+// ClaimedBy lists the callers that uploaded this content and have not released it
+// with DELETE /api/artifact; the blob is only removed once none is left and no script
+// or request refers to it. ClaimedAt is the time of the latest claim and gives a
+// deduplicated upload the same grace period against the sweeper as a new one.
+type artifactBlob struct {
+	ArtifactID  string    `bson:"_id"`
+	SHA256      string    `bson:"sha256,omitempty"` // unset for artifacts stored without a digest
+	Name        string    `bson:"name"`
+	ContentType string    `bson:"content_type"`
+	Size        int64     `bson:"size"`
+	UploadedAt  time.Time `bson:"uploaded_at"`
+	UploadedBy  string    `bson:"uploaded_by"`
+	ClaimedBy   []string  `bson:"claimed_by"`
+	ClaimedAt   time.Time `bson:"claimed_at"`
+}
+
+// newArtifactBlob catalogues a stored artifact claimed by its uploader
+func newArtifactBlob(info artifactstore.Info) artifactBlob {
+	return artifactBlob{
+		ArtifactID:  info.ID,
+		SHA256:      info.SHA256,
+		Name:        info.Name,
+		ContentType: info.ContentType,
+		Size:        info.Size,
+		UploadedAt:  info.UploadedAt,
+		UploadedBy:  info.UploadedBy,
+		ClaimedBy:   []string{info.UploadedBy},
+		ClaimedAt:   info.UploadedAt,
+	}
+}
+
+// info describes the artifact the way the store would
+func (b artifactBlob) info() artifactstore.Info {
+	return artifactstore.Info{
+		ID:          b.ArtifactID,
+		Name:        b.Name,
+		ContentType: b.ContentType,
+		Size:        b.Size,
+		UploadedAt:  b.UploadedAt.UTC(),
+		UploadedBy:  b.UploadedBy,
+		SHA256:      b.SHA256,
+	}
+}
+
+// claimArtifactByDigest claims the stored artifact with the given SHA-256 digest for
+// caller. It reports false when there is none. The claim is taken before the blob is
+// checked, so removeArtifact, which drops the entry first, cannot take the blob away
+// from a claim it did not see; an entry whose blob has gone anyway is dropped.
+func claimArtifactByDigest(ctx context.Context, database *mongo.Database, store artifactstore.Store, digest, caller string) (artifactstore.Info, bool, error) {
+	blobs := database.Collection(artifactBlobsCollection)
+
+	var blob artifactBlob
+	err := blobs.FindOneAndUpdate(ctx,
+		bson.M{"sha256": digest},
+		bson.M{
+			"$addToSet": bson.M{"claimed_by": caller},
+			"$set":      bson.M{"claimed_at": time.Now().UTC()},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&blob)
+	if err == mongo.ErrNoDocuments {
+		return artifactstore.Info{}, false, nil
+	}
+	if err != nil {
+		return artifactstore.Info{}, false, err
+	}
+
+	info, err := store.Stat(ctx, blob.ArtifactID)
+	if err == artifactstore.ErrNotFound {
+		_, err = blobs.DeleteOne(ctx, bson.M{"_id": blob.ArtifactID})
+		return artifactstore.Info{}, false, err
+	}
+	if err != nil {
+		return artifactstore.Info{}, false, err
+	}
+	return info, true, nil
+}
+
+// registerArtifactBlob records a newly stored artifact claimed by its uploader. A
+// duplicate key error means an identical upload registered first.
+func registerArtifactBlob(ctx context.Context, database *mongo.Database, info artifactstore.Info) error {
+	_, err := database.Collection(artifactBlobsCollection).InsertOne(ctx, newArtifactBlob(info))
+	return err
+}
+
+// syncArtifactBlobs brings artifact_blobs in line with the artifacts a walk of the
+// store found at walkedAt. Artifacts without an entry (stored before the catalogue
+// existed, or whose registration failed) are added; an artifact whose digest another
+// entry already holds is added without it. Entries of artifacts that were not found
+// are dropped unless they were written after the walk started.
+func syncArtifactBlobs(ctx context.Context, database *mongo.Database, stored []artifactstore.Info, walkedAt time.Time) error {
+	blobs := database.Collection(artifactBlobsCollection)
+
+	cursor, err := blobs.Find(ctx, bson.M{}, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return err
+	}
+	var entries []artifactBlob
+	if err := cursor.All(ctx, &entries); err != nil {
+		return err
+	}
+	catalogued := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		catalogued[entry.ArtifactID] = true
+	}
+
+	found := make(map[string]bool, len(stored))
+	for _, info := range stored {
+		found[info.ID] = true
+		if catalogued[info.ID] {
+			continue
+		}
+		blob := newArtifactBlob(info)
+		blob.ClaimedBy = []string{} // its uploads predate claims
+		_, err := blobs.InsertOne(ctx, blob)
+		if mongo.IsDuplicateKeyError(err) && blob.SHA256 != "" {
+			blob.SHA256 = ""
+			_, err = blobs.InsertOne(ctx, blob)
+		}
+		if err != nil && !mongo.IsDuplicateKeyError(err) {
+			return err
+		}
+	}
+
+	var missing []string
+	for id := range catalogued {
+		if !found[id] {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	_, err = blobs.DeleteMany(ctx, bson.M{
+		"_id":         bson.M{"$in": missing},
+		"uploaded_at": bson.M{"$lt": walkedAt},
+		"claimed_at":  bson.M{"$lt": walkedAt},
+	})
+	return err
+}
+
+// releaseArtifactClaim drops caller's claim on an artifact. It returns the callers
+// still claiming it and whether caller held a claim; artifacts without an entry, or
+// catalogued before claims were recorded, have no claims.
+func releaseArtifactClaim(ctx context.Context, database *mongo.Database, artifactID, caller string) ([]string, bool, error) {
+	var blob artifactBlob
+	err := database.Collection(artifactBlobsCollection).FindOneAndUpdate(ctx,
+		bson.M{"_id": artifactID},
+		bson.M{"$pull": bson.M{"claimed_by": caller}},
+		options.FindOneAndUpdate().SetReturnDocument(options.Before),
+	).Decode(&blob)
+	if err == mongo.ErrNoDocuments {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	remaining := []string{}
+	held := false
+	for _, claimant := range blob.ClaimedBy {
+		if claimant == caller {
+			held = true
+			continue
+		}
+		remaining = append(remaining, claimant)
+	}
+	return remaining, held, nil
+}
+
+// recentlyClaimedArtifacts returns the artifacts claimed by an upload after cutoff
+func recentlyClaimedArtifacts(ctx context.Context, database *mongo.Database, cutoff time.Time) (map[string]bool, error) {
+	opts := options.Find().SetProjection(bson.M{"_id": 1})
+	cursor, err := database.Collection(artifactBlobsCollection).Find(ctx, bson.M{"claimed_at": bson.M{"$gt": cutoff}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var blobs []artifactBlob
+	if err := cursor.All(ctx, &blobs); err != nil {
+		return nil, err
+	}
+
+	claimed := make(map[string]bool, len(blobs))
+	for _, blob := range blobs {
+		claimed[blob.ArtifactID] = true
+	}
+	return claimed, nil
+}
+
+// unclaimedArtifact matches catalogue entries nobody claims
+var unclaimedArtifact = bson.M{"claimed_by.0": bson.M{"$exists": false}}
+
+// claimedBefore matches catalogue entries not claimed since cutoff
+func claimedBefore(cutoff time.Time) bson.M {
+	return bson.M{"claimed_at": bson.M{"$lte": cutoff}}
+}
+
+// removeArtifact deletes an artifact together with its catalogue entry, provided the
+// entry still matches unclaimed. The entry is deleted first: an upload claiming the
+// artifact meanwhile either finds no entry and stores its own copy, or keeps the
+// artifact and removeArtifact reports errArtifactClaimed. ErrNotFound reports a blob
+// that was already gone.
+func removeArtifact(ctx context.Context, database *mongo.Database, store artifactstore.Store, artifactID string, unclaimed bson.M) error {
+	blobs := database.Collection(artifactBlobsCollection)
+
+	filter := bson.M{"_id": artifactID}
+	for key, value := range unclaimed {
+		filter[key] = value
+	}
+	result, err := blobs.DeleteOne(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		catalogued, err := blobs.CountDocuments(ctx, bson.M{"_id": artifactID})
+		if err != nil {
+			return err
+		}
+		if catalogued > 0 {
+			return errArtifactClaimed
+		}
+	}
+	return store.Delete(ctx, artifactID)
+}
@@ -0,0 +1,591 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"VCCwebsite/internal/artifactstore"
+	scripts "VCCwebsite/internal/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// artifactSortFields are the sort= names accepted by GET /api/artifacts
+var artifactSortFields = map[string]string{
+	"name":         "name",
+	"content_type": "content_type",
+	"size":         "size",
+	"uploaded_at":  "uploaded_at",
+	"uploaded_by":  "uploaded_by",
+}
+
+// artifactListing is one stored artifact with the live records it is attached to
+type artifactListing struct {
+	scripts.Artifact
+	Scripts  []string `json:"scripts,omitempty"`
+	Requests []string `json:"requests,omitempty"`
+}
+
+// artifactFromInfo describes a stored artifact the way scripts embed it
+func artifactFromInfo(info artifactstore.Info) scripts.Artifact {
+	return scripts.Artifact{
+		ID:           info.ID,
+		Name:         info.Name,
+		ContentType:  info.ContentType,
+		Size:         info.Size,
+		UploadedAt:   info.UploadedAt.Format(time.RFC3339),
+		UploadedBy:   info.UploadedBy,
+		URL:          fmt.Sprintf("/api/artifact?id=%s", info.ID),
+		ThumbnailURL: thumbnailURL(info),
+	}
+}
+
+// artifactIDFromRequest reads the artifact ID from ?id= or the last path segment, so
+// /api/artifact?id=xxx and /api/artifacts/xxx name the same file
+func artifactIDFromRequest(r *http.Request) string {
+	if id := r.URL.Query().Get("id"); id != "" {
+		return id
+	}
+	path := strings.TrimSuffix(r.URL.Path, "/")
+	for _, prefix := range []string{"/api/artifacts/", "/api/artifact/"} {
+		if strings.HasPrefix(path, prefix) {
+			return strings.TrimPrefix(path, prefix)
+		}
+	}
+	return ""
+}
+
+// handleListArtifacts lists the stored artifacts
+// GET /api/artifacts
+// GET /api/artifacts?content_type=image/ - exact type, or a prefix ending in "/"
+// GET /api/artifacts?uploaded_by=xxx
+// GET /api/artifacts?uploaded_after=2024-01-01&uploaded_before=2024-02-01T00:00:00Z
+// GET /api/artifacts?name=xxx - file name contains
+// GET /api/artifacts?script_id=xxx - attached to that script
+// GET /api/artifacts?attached=false - attached to no live script or request
+// Also takes page=, limit= and sort= (default -uploaded_at) with X-Total-Count.
+// The listing is queried from artifact_blobs. Without MongoDB the store is walked
+// instead and attachments are not reported.
+func handleListArtifacts(w http.ResponseWriter, r *http.Request, client *mongo.Client, store artifactstore.Store) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	query := r.URL.Query()
+	params, err := parseListParams(r, artifactSortFields, sortKey{Field: "uploaded_at", Desc: true})
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	filter := artifactFilter{
+		ContentType: strings.ToLower(query.Get("content_type")),
+		UploadedBy:  query.Get("uploaded_by"),
+		Name:        query.Get("name"),
+	}
+	if raw := query.Get("uploaded_after"); raw != "" {
+		if filter.After, err = parseListDate(raw); err != nil {
+			respondWithError(w, http.StatusBadRequest, "uploaded_after: "+err.Error())
+			return
+		}
+	}
+	if raw := query.Get("uploaded_before"); raw != "" {
+		if filter.Before, err = parseListDate(raw); err != nil {
+			respondWithError(w, http.StatusBadRequest, "uploaded_before: "+err.Error())
+			return
+		}
+	}
+
+	scriptID := query.Get("script_id")
+	attached := query.Get("attached")
+	if attached != "" && attached != "true" && attached != "false" {
+		respondWithError(w, http.StatusBadRequest, "attached must be true or false")
+		return
+	}
+
+	if client == nil {
+		if scriptID != "" || attached != "" {
+			respondWithError(w, http.StatusServiceUnavailable, "Database connection not available")
+			return
+		}
+		listStoredArtifacts(ctx, w, store, filter, params)
+		return
+	}
+
+	database := client.Database("vccwebsite")
+	attachments, err := artifactAttachments(ctx, database)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving artifact attachments")
+		return
+	}
+
+	mongoFilter := filter.bson()
+	ids := bson.M{}
+	if scriptID != "" {
+		matching := []string{}
+		for artifactID, links := range attachments {
+			if containsString(links.Scripts, scriptID) {
+				matching = append(matching, artifactID)
+			}
+		}
+		ids["$in"] = matching
+	}
+	if attached != "" {
+		attachedIDs := make([]string, 0, len(attachments))
+		for artifactID := range attachments {
+			attachedIDs = append(attachedIDs, artifactID)
+		}
+		if attached == "false" {
+			ids["$nin"] = attachedIDs
+		} else if scriptID == "" {
+			ids["$in"] = attachedIDs
+		}
+	}
+	if len(ids) > 0 {
+		mongoFilter["_id"] = ids
+	}
+
+	collection := database.Collection(artifactBlobsCollection)
+	total, err := collection.CountDocuments(ctx, mongoFilter)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error listing artifacts")
+		return
+	}
+	cursor, err := collection.Find(ctx, mongoFilter, params.findOptions())
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error listing artifacts")
+		return
+	}
+	var blobs []artifactBlob
+	if err := cursor.All(ctx, &blobs); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error listing artifacts")
+		return
+	}
+
+	listings := make([]artifactListing, 0, len(blobs))
+	for _, blob := range blobs {
+		listing := artifactListing{Artifact: artifactFromInfo(blob.info())}
+		if links, ok := attachments[blob.ArtifactID]; ok {
+			listing.Scripts = links.Scripts
+			listing.Requests = links.Requests
+		}
+		listings = append(listings, listing)
+	}
+
+	setListHeaders(w, params, total)
+	respondWithJSON(w, http.StatusOK, listings)
+}
+
+// listStoredArtifacts answers GET /api/artifacts by walking the store, for when there
+// is no artifact_blobs to query
+func listStoredArtifacts(ctx context.Context, w http.ResponseWriter, store artifactstore.Store, filter artifactFilter, params listParams) {
+	listings := []artifactListing{}
+	err := store.Walk(ctx, func(info artifactstore.Info) error {
+		if filter.matches(info) {
+			listings = append(listings, artifactListing{Artifact: artifactFromInfo(info)})
+		}
+		return nil
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error listing artifacts")
+		return
+	}
+
+	sortArtifactListings(listings, params.Sort)
+
+	total := len(listings)
+	if params.Limit > 0 {
+		start := params.Offset()
+		if start > total {
+			start = total
+		}
+		end := start + params.Limit
+		if end > total {
+			end = total
+		}
+		listings = listings[start:end]
+	}
+
+	setListHeaders(w, params, int64(total))
+	respondWithJSON(w, http.StatusOK, listings)
+}
+
+// artifactFilter holds the metadata filters of GET /api/artifacts
+type artifactFilter struct {
+	ContentType string // lower case; a trailing "/" matches the whole type
+	UploadedBy  string
+	Name        string
+	After       time.Time
+	Before      time.Time
+}
+
+// bson is the filter as a query on artifact_blobs
+func (f artifactFilter) bson() bson.M {
+	filter := bson.M{}
+	switch {
+	case strings.HasSuffix(f.ContentType, "/"):
+		filter["content_type"] = bson.M{"$regex": "^" + regexp.QuoteMeta(f.ContentType), "$options": "i"}
+	case f.ContentType != "":
+		filter["content_type"] = bson.M{"$regex": "^" + regexp.QuoteMeta(f.ContentType) + "$", "$options": "i"}
+	}
+	if f.UploadedBy != "" {
+		filter["uploaded_by"] = bson.M{"$regex": "^" + regexp.QuoteMeta(f.UploadedBy) + "$", "$options": "i"}
+	}
+	if f.Name != "" {
+		filter["name"] = containsFilter(f.Name)
+	}
+	uploadedAt := bson.M{}
+	if !f.After.IsZero() {
+		uploadedAt["$gte"] = f.After
+	}
+	if !f.Before.IsZero() {
+		uploadedAt["$lt"] = f.Before
+	}
+	if len(uploadedAt) > 0 {
+		filter["uploaded_at"] = uploadedAt
+	}
+	return filter
+}
+
+// matches applies the filter to an artifact of the store
+func (f artifactFilter) matches(info artifactstore.Info) bool {
+	contentType := strings.ToLower(info.ContentType)
+	switch {
+	case strings.HasSuffix(f.ContentType, "/") && !strings.HasPrefix(contentType, f.ContentType),
+		f.ContentType != "" && !strings.HasSuffix(f.ContentType, "/") && contentType != f.ContentType,
+		f.UploadedBy != "" && !strings.EqualFold(info.UploadedBy, f.UploadedBy),
+		f.Name != "" && !strings.Contains(strings.ToLower(info.Name), strings.ToLower(f.Name)),
+		!f.After.IsZero() && info.UploadedAt.Before(f.After),
+		!f.Before.IsZero() && !info.UploadedAt.Before(f.Before):
+		return false
+	}
+	return true
+}
+
+// parseListDate accepts an RFC 3339 timestamp or a plain date (midnight UTC)
+func parseListDate(raw string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", raw)
+	if err != nil {
+		return t, fmt.Errorf("use YYYY-MM-DD or an RFC 3339 timestamp")
+	}
+	return t, nil
+}
+
+// sortArtifactListings orders the listings by the sort keys, then by ID
+func sortArtifactListings(listings []artifactListing, keys []sortKey) {
+	sort.SliceStable(listings, func(i, j int) bool {
+		a, b := listings[i], listings[j]
+		for _, key := range keys {
+			var cmp int
+			switch key.Field {
+			case "name":
+				cmp = strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+			case "content_type":
+				cmp = strings.Compare(a.ContentType, b.ContentType)
+			case "uploaded_by":
+				cmp = strings.Compare(strings.ToLower(a.UploadedBy), strings.ToLower(b.UploadedBy))
+			case "uploaded_at":
+				cmp = strings.Compare(a.UploadedAt, b.UploadedAt) // RFC 3339 in UTC sorts as text
+			case "size":
+				switch {
+				case a.Size < b.Size:
+					cmp = -1
+				case a.Size > b.Size:
+					cmp = 1
+				}
+			}
+			if key.Desc {
+				cmp = -cmp
+			}
+			if cmp != 0 {
+				return cmp < 0
+			}
+		}
+		return a.ID < b.ID
+	})
+}
+
+// artifactAttachments maps each artifact ID to the live scripts and requests showing it
+func artifactAttachments(ctx context.Context, database *mongo.Database) (map[string]*artifactListing, error) {
+	attachments := map[string]*artifactListing{}
+	for _, kind := range referenceKinds {
+		if kind.Target != "artifact" || kind.OnDelete != onDeleteBlock {
+			continue // versions are history, not attachments
+		}
+		opts := options.Find().SetProjection(bson.M{kind.Field: 1})
+		cursor, err := database.Collection(kind.Collection).Find(ctx, bson.M{
+			kind.Field:     bson.M{"$exists": true},
+			deletedAtField: notTrashed,
+		}, opts)
+		if err != nil {
+			return nil, err
+		}
+		var records []bson.M
+		if err := cursor.All(ctx, &records); err != nil {
+			return nil, err
+		}
+
+		for _, record := range records {
+			recordID, _ := record["_id"].(primitive.ObjectID)
+			for _, value := range valuesAt(record, strings.Split(kind.Field, ".")) {
+				artifactID, ok := value.(string)
+				if !ok || artifactID == "" {
+					continue
+				}
+				links := attachments[artifactID]
+				if links == nil {
+					links = &artifactListing{}
+					attachments[artifactID] = links
+				}
+				if kind.Collection == "scripts" {
+					links.Scripts = appendUnique(links.Scripts, recordID.Hex())
+				} else {
+					links.Requests = appendUnique(links.Requests, recordID.Hex())
+				}
+			}
+		}
+	}
+	return attachments, nil
+}
+
+func appendUnique(values []string, value string) []string {
+	if containsString(values, value) {
+		return values
+	}
+	return append(values, value)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// attachArtifactRequest is the body of POST /api/document/artifacts
+type attachArtifactRequest struct {
+	ArtifactID string `json:"artifact_id"`
+}
+
+// DocumentArtifactsHandler manages the artifacts attached to one script, so clients
+// do not have to PUT the whole Artifacts array (and race other editors) to change it.
+// Changes need If-Match like any other edit; each saves a version of the script and
+// returns the new ETag.
+// GET    /api/document/artifacts?id=xxx
+// POST   /api/document/artifacts?id=xxx            {"artifact_id": "..."}
+// DELETE /api/document/artifacts?id=xxx&artifact_id=yyy
+func DocumentArtifactsHandler(client *mongo.Client, store artifactstore.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if client == nil {
+			respondWithError(w, http.StatusServiceUnavailable, "Database connection not available")
+			return
+		}
+
+		collection := client.Database("vccwebsite").Collection("scripts")
+		versionsCollection := client.Database("vccwebsite").Collection("scripts_versions")
+
+		switch r.Method {
+		case http.MethodGet:
+			handleGetDocumentArtifacts(w, r, collection, versionsCollection)
+		case http.MethodPost:
+			if store == nil {
+				respondWithError(w, http.StatusServiceUnavailable, "Artifact storage not available")
+				return
+			}
+			handleAttachDocumentArtifact(w, r, collection, versionsCollection, store)
+		case http.MethodDelete:
+			handleDetachDocumentArtifact(w, r, collection, versionsCollection)
+		default:
+			respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	}
+}
+
+func handleGetDocumentArtifacts(w http.ResponseWriter, r *http.Request, collection *mongo.Collection, versionsCollection *mongo.Collection) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	doc, ok := findProjectedDocument(ctx, w, r, collection, "artifacts")
+	if !ok {
+		return
+	}
+	objectID, _ := primitive.ObjectIDFromHex(r.URL.Query().Get("id"))
+	current, err := currentVersionNumber(ctx, versionsCollection, objectID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving document version")
+		return
+	}
+	w.Header().Set("ETag", documentETag(current))
+	respondWithArtifacts(w, doc.Artifacts)
+}
+
+// documentIfMatch checks the If-Match header against the current version of the
+// script and returns that version
+func documentIfMatch(ctx context.Context, w http.ResponseWriter, r *http.Request, versionsCollection *mongo.Collection, objectID primitive.ObjectID) (int, bool) {
+	current, err := currentVersionNumber(ctx, versionsCollection, objectID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving document version")
+		return 0, false
+	}
+	if !checkIfMatch(w, r, current) {
+		return 0, false
+	}
+	return current, true
+}
+
+func handleAttachDocumentArtifact(w http.ResponseWriter, r *http.Request, collection *mongo.Collection, versionsCollection *mongo.Collection, store artifactstore.Store) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var body attachArtifactRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && err != io.EOF {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	if body.ArtifactID == "" {
+		respondWithError(w, http.StatusBadRequest, "artifact_id is required")
+		return
+	}
+
+	doc, ok := findProjectedDocument(ctx, w, r, collection, "artifacts")
+	if !ok {
+		return
+	}
+	objectID, _ := primitive.ObjectIDFromHex(r.URL.Query().Get("id"))
+	current, ok := documentIfMatch(ctx, w, r, versionsCollection, objectID)
+	if !ok {
+		return
+	}
+	for _, artifact := range doc.Artifacts {
+		if artifact.ID == body.ArtifactID {
+			w.Header().Set("ETag", documentETag(current))
+			respondWithArtifacts(w, doc.Artifacts) // already attached
+			return
+		}
+	}
+
+	info, err := store.Stat(ctx, body.ArtifactID)
+	if err != nil {
+		if err == artifactstore.ErrNotFound {
+			respondWithError(w, http.StatusUnprocessableEntity, "Artifact "+body.ArtifactID+" does not exist")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving artifact")
+		return
+	}
+
+	filter := liveFilter(objectID)
+	filter["artifacts.id"] = bson.M{"$ne": info.ID}
+	update := bson.M{"$push": bson.M{"artifacts": artifactFromInfo(info)}}
+
+	updateDocumentArtifacts(ctx, w, r, collection, versionsCollection, objectID, current, filter, update, "Attached artifact "+info.Name)
+}
+
+func handleDetachDocumentArtifact(w http.ResponseWriter, r *http.Request, collection *mongo.Collection, versionsCollection *mongo.Collection) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	artifactID := r.URL.Query().Get("artifact_id")
+	if artifactID == "" {
+		respondWithError(w, http.StatusBadRequest, "artifact_id is required")
+		return
+	}
+
+	doc, ok := findProjectedDocument(ctx, w, r, collection, "artifacts")
+	if !ok {
+		return
+	}
+	objectID, _ := primitive.ObjectIDFromHex(r.URL.Query().Get("id"))
+	current, ok := documentIfMatch(ctx, w, r, versionsCollection, objectID)
+	if !ok {
+		return
+	}
+	var detached *scripts.Artifact
+	for i := range doc.Artifacts {
+		if doc.Artifacts[i].ID == artifactID {
+			detached = &doc.Artifacts[i]
+			break
+		}
+	}
+	if detached == nil {
+		respondWithError(w, http.StatusNotFound, "Artifact is not attached to this document")
+		return
+	}
+
+	filter := liveFilter(objectID)
+	filter["artifacts.id"] = artifactID
+	update := bson.M{"$pull": bson.M{"artifacts": bson.M{"id": artifactID}}}
+
+	updateDocumentArtifacts(ctx, w, r, collection, versionsCollection, objectID, current, filter, update, "Detached artifact "+detached.Name)
+}
+
+// updateDocumentArtifacts saves a version on top of current, applies the attach or
+// detach update and responds with the resulting artifact list. The filter only matches
+// while the change still applies; when it no longer does, the version is taken back
+// so the ETag only moves when the script changed.
+func updateDocumentArtifacts(ctx context.Context, w http.ResponseWriter, r *http.Request, collection *mongo.Collection, versionsCollection *mongo.Collection, objectID primitive.ObjectID, current int, filter bson.M, update bson.M, changeNote string) {
+	savedVersion, err := saveVersion(ctx, collection, versionsCollection, objectID, changeNote, callerIdentity(r), current)
+	if err == errVersionConflict {
+		respondWithStaleVersion(ctx, w, versionsCollection, objectID)
+		return
+	}
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			respondWithError(w, http.StatusNotFound, "Document not found")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Error saving version before update")
+		return
+	}
+
+	update["$set"] = bson.M{"updated_at": time.Now().UTC().Format(time.RFC3339)}
+	result, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error updating document artifacts")
+		return
+	}
+	if result.MatchedCount == 0 {
+		// Trashed or changed by a writer that skipped the version counter
+		_, _ = versionsCollection.DeleteOne(ctx, bson.M{"document_id": objectID, "version_number": savedVersion})
+		releaseVersionNumber(ctx, versionsCollection, objectID, savedVersion)
+		if err := collection.FindOne(ctx, liveFilter(objectID)).Err(); err == mongo.ErrNoDocuments {
+			respondWithError(w, http.StatusNotFound, "Document not found")
+			return
+		}
+		respondWithError(w, http.StatusConflict, "Document artifacts changed concurrently, reload and retry")
+		return
+	}
+
+	var doc scripts.StandardizedScript
+	opts := options.FindOne().SetProjection(bson.M{"artifacts": 1})
+	if err := collection.FindOne(ctx, bson.M{"_id": objectID}, opts).Decode(&doc); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving updated document")
+		return
+	}
+
+	w.Header().Set("ETag", documentETag(savedVersion))
+	respondWithArtifacts(w, doc.Artifacts)
+}
+
+func respondWithArtifacts(w http.ResponseWriter, artifacts []scripts.Artifact) {
+	if artifacts == nil {
+		artifacts = []scripts.Artifact{}
+	}
+	respondWithJSON(w, http.StatusOK, artifacts)
+}
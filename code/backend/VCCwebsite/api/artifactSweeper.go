@@ -0,0 +1,252 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"VCCwebsite/internal/artifactstore"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// defaultArtifactGrace applies when ARTIFACT_GC_GRACE_HOURS is unset. An upload is
+// attached by the save that follows it, so a day leaves plenty of room for slow forms.
+const defaultArtifactGrace = 24 * time.Hour
+
+// artifactSweepInterval is how often StartArtifactSweeper looks for orphans
+const artifactSweepInterval = time.Hour
+
+// ArtifactGraceFromEnv reads ARTIFACT_GC_GRACE_HOURS, how old an unreferenced upload
+// must be before the sweeper deletes it. 0 turns the sweeper off.
+func ArtifactGraceFromEnv() (time.Duration, error) {
+	raw := os.Getenv("ARTIFACT_GC_GRACE_HOURS")
+	if raw == "" {
+		return defaultArtifactGrace, nil
+	}
+	hours, err := strconv.Atoi(raw)
+	if err != nil || hours < 0 {
+		return 0, fmt.Errorf("ARTIFACT_GC_GRACE_HOURS must be a non-negative number of hours, got %q", raw)
+	}
+	return time.Duration(hours) * time.Hour, nil
+}
+
+// orphanedArtifact is a stored file that no script, version or request refers to
+type orphanedArtifact struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	Size       int64     `json:"size"`
+	UploadedAt time.Time `json:"uploaded_at"`
+}
+
+// artifactSweepReport is the outcome of one sweep, or what a dry run would delete
+type artifactSweepReport struct {
+	DryRun  bool               `json:"dry_run"`
+	Cutoff  time.Time          `json:"cutoff"` // only files uploaded before this are considered
+	Orphans []orphanedArtifact `json:"orphans"`
+	Bytes   int64              `json:"bytes"`
+	Deleted int                `json:"deleted"`
+}
+
+// ArtifactOrphansHandler reports or deletes the orphaned artifacts. With the sweeper
+// turned off (grace 0) it still leaves uploads younger than defaultArtifactGrace
+// alone, as they may not have been saved onto a script yet.
+// GET    /api/artifact/orphans - dry run: list what the sweeper would delete
+// DELETE /api/artifact/orphans - sweep now
+func ArtifactOrphansHandler(client *mongo.Client, store artifactstore.Store, grace time.Duration) http.HandlerFunc {
+	if grace <= 0 {
+		grace = defaultArtifactGrace
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if client == nil {
+			respondWithError(w, http.StatusServiceUnavailable, "Database connection not available")
+			return
+		}
+		if store == nil {
+			respondWithError(w, http.StatusServiceUnavailable, "Artifact storage not available")
+			return
+		}
+
+		var dryRun bool
+		switch r.Method {
+		case http.MethodGet:
+			dryRun = true
+		case http.MethodDelete:
+		default:
+			respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		report, err := sweepArtifacts(ctx, client.Database("vccwebsite"), store, grace, dryRun)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error sweeping artifacts")
+			return
+		}
+		respondWithJSON(w, http.StatusOK, report)
+	}
+}
+
+// referencedArtifactIDs collects every artifact ID held by a script, version or request
+func referencedArtifactIDs(ctx context.Context, database *mongo.Database) (map[string]bool, error) {
+	ids := map[string]bool{}
+	for _, kind := range referenceKinds {
+		if kind.Target != "artifact" {
+			continue
+		}
+		values, err := database.Collection(kind.Collection).Distinct(ctx, kind.Field, bson.M{})
+		if err != nil {
+			return nil, err
+		}
+		for _, value := range values {
+			if id, ok := value.(string); ok {
+				ids[id] = true
+			}
+		}
+	}
+	return ids, nil
+}
+
+// sweepArtifacts finds the stored artifacts uploaded more than grace ago that nothing
+// refers to, and deletes them unless dryRun is set. Each orphan is re-checked just
+// before it is deleted in case a save attached it during the sweep.
+func sweepArtifacts(ctx context.Context, database *mongo.Database, store artifactstore.Store, grace time.Duration, dryRun bool) (artifactSweepReport, error) {
+	report := artifactSweepReport{
+		DryRun:  dryRun,
+		Cutoff:  time.Now().UTC().Add(-grace),
+		Orphans: []orphanedArtifact{},
+	}
+
+	referenced, err := referencedArtifactIDs(ctx, database)
+	if err != nil {
+		return report, err
+	}
+
+	// A deduplicated upload restarts the grace period of the artifact it was given
+	claimed, err := recentlyClaimedArtifacts(ctx, database, report.Cutoff)
+	if err != nil {
+		return report, err
+	}
+
+	// Collect first so deletes do not disturb the walk
+	walkedAt := time.Now().UTC()
+	stored, err := walkArtifacts(ctx, store)
+	if err != nil {
+		return report, err
+	}
+	if !dryRun {
+		if err := syncArtifactBlobs(ctx, database, stored, walkedAt); err != nil {
+			return report, err
+		}
+	}
+
+	var candidates []artifactstore.Info
+	for _, info := range stored {
+		if info.UploadedAt.Before(report.Cutoff) && !referenced[info.ID] && !claimed[info.ID] {
+			candidates = append(candidates, info)
+		}
+	}
+
+	for _, info := range candidates {
+		if !dryRun {
+			objectID, err := primitive.ObjectIDFromHex(info.ID)
+			if err != nil {
+				continue
+			}
+			refs, err := findReferences(ctx, database, "artifact", objectID)
+			if err != nil {
+				return report, err
+			}
+			if len(refs) > 0 {
+				continue
+			}
+			err = removeArtifact(ctx, database, store, info.ID, claimedBefore(report.Cutoff))
+			if err == errArtifactClaimed {
+				continue
+			}
+			if err != nil && err != artifactstore.ErrNotFound {
+				return report, err
+			}
+			report.Deleted++
+		}
+		report.Orphans = append(report.Orphans, orphanedArtifact{
+			ID:         info.ID,
+			Name:       info.Name,
+			Size:       info.Size,
+			UploadedAt: info.UploadedAt,
+		})
+		report.Bytes += info.Size
+	}
+	return report, nil
+}
+
+// walkArtifacts returns every artifact in the store
+func walkArtifacts(ctx context.Context, store artifactstore.Store) ([]artifactstore.Info, error) {
+	var stored []artifactstore.Info
+	err := store.Walk(ctx, func(info artifactstore.Info) error {
+		stored = append(stored, info)
+		return nil
+	})
+	return stored, err
+}
+
+// StartArtifactSweeper deletes orphaned artifacts now and then every
+// artifactSweepInterval until ctx is cancelled. Each sweep also catalogues the
+// artifacts missing from artifact_blobs; with the sweeper turned off that is done
+// once now.
+func StartArtifactSweeper(ctx context.Context, client *mongo.Client, store artifactstore.Store, grace time.Duration) {
+	if client == nil || store == nil {
+		return
+	}
+	database := client.Database("vccwebsite")
+	if grace <= 0 {
+		go func() {
+			sctx, cancel := context.WithTimeout(ctx, time.Minute)
+			defer cancel()
+			walkedAt := time.Now().UTC()
+			stored, err := walkArtifacts(sctx, store)
+			if err == nil {
+				err = syncArtifactBlobs(sctx, database, stored, walkedAt)
+			}
+			if err != nil {
+				log.Printf("cataloguing artifacts failed: %v", err)
+			}
+		}()
+		return
+	}
+	sweep := func() {
+		sctx, cancel := context.WithTimeout(ctx, time.Minute)
+		defer cancel()
+		report, err := sweepArtifacts(sctx, database, store, grace, false)
+		if err != nil {
+			log.Printf("artifact sweep failed: %v", err)
+		}
+		if report.Deleted > 0 {
+			log.Printf("artifact sweep removed %d orphaned file(s), %d bytes", report.Deleted, report.Bytes)
+		}
+	}
+
+	go func() {
+		sweep()
+		ticker := time.NewTicker(artifactSweepInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				sweep()
+			}
+		}
+	}()
+}
@@ -0,0 +1,183 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"VCCwebsite/internal/artifactstore"
+	"VCCwebsite/internal/thumbnail"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// thumbnailSizes are the square bounds thumbnails are made in; the first is the default
+var thumbnailSizes = []int{128, 512}
+
+func thumbnailVariant(size int) string {
+	return fmt.Sprintf("thumb-%d", size)
+}
+
+// thumbnailURL links an artifact's default thumbnail, if its type has one
+func thumbnailURL(info artifactstore.Info) string {
+	if !thumbnail.Supported(info.ContentType) {
+		return ""
+	}
+	return fmt.Sprintf("/api/artifact/thumbnail?id=%s", info.ID)
+}
+
+// storeThumbnails renders every thumbnail size of a new upload next to it. Failures
+// only cost the upload its thumbnails up front; they are retried on first request.
+func storeThumbnails(ctx context.Context, store artifactstore.Store, info artifactstore.Info, content []byte) {
+	if !thumbnail.Supported(info.ContentType) {
+		return
+	}
+	if _, err := renderThumbnails(ctx, store, info, content); err != nil {
+		log.Printf("Thumbnails of artifact %s failed: %v", info.ID, err)
+	}
+}
+
+// renderThumbnails renders every thumbnail size from one decode of the artifact and
+// stores them. Images that cannot be rendered (above thumbnail.MaxPixels, or corrupt)
+// get placeholders stored instead, so their thumbnail URL keeps working and the
+// original is not read again on every request. The rendered thumbnails are returned
+// even when storing them fails.
+func renderThumbnails(ctx context.Context, store artifactstore.Store, info artifactstore.Info, content []byte) ([]thumbnail.Thumbnail, error) {
+	thumbnails, err := thumbnail.Render(info.ContentType, content, thumbnailSizes...)
+	if err == thumbnail.ErrUnsupported {
+		return nil, err
+	}
+	if err != nil {
+		log.Printf("Thumbnails of artifact %s use a placeholder: %v", info.ID, err)
+		if thumbnails, err = thumbnail.Placeholders(thumbnailSizes...); err != nil {
+			return nil, err
+		}
+	}
+	for _, thumb := range thumbnails {
+		err := store.PutVariant(ctx, info.ID, thumbnailVariant(thumb.Size), thumb.ContentType, bytes.NewReader(thumb.Content))
+		if err != nil {
+			return thumbnails, err
+		}
+	}
+	return thumbnails, nil
+}
+
+// ArtifactThumbnailHandler serves artifact thumbnails, rendering and storing any that
+// are missing (artifacts uploaded before thumbnails existed, or failed renders)
+// GET /api/artifact/thumbnail?id=xxx
+// GET /api/artifact/thumbnail?id=xxx&size=512
+func ArtifactThumbnailHandler(store artifactstore.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if store == nil {
+			respondWithError(w, http.StatusServiceUnavailable, "Artifact storage not available")
+			return
+		}
+		if r.Method != http.MethodGet {
+			respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		artifactID := r.URL.Query().Get("id")
+		if artifactID == "" {
+			respondWithError(w, http.StatusBadRequest, "Artifact ID is required")
+			return
+		}
+		if !primitive.IsValidObjectID(artifactID) {
+			respondWithError(w, http.StatusBadRequest, "Invalid artifact ID format")
+			return
+		}
+
+		size := thumbnailSizes[0]
+		if raw := r.URL.Query().Get("size"); raw != "" {
+			size, _ = strconv.Atoi(raw)
+			if !containsInt(thumbnailSizes, size) {
+				respondWithError(w, http.StatusBadRequest, fmt.Sprintf("size must be one of %v", thumbnailSizes))
+				return
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		content, info, err := openThumbnail(ctx, store, artifactID, size)
+		if err != nil {
+			switch err {
+			case artifactstore.ErrNotFound:
+				respondWithError(w, http.StatusNotFound, "Artifact not found")
+			case thumbnail.ErrUnsupported:
+				respondWithError(w, http.StatusNotFound, "No thumbnail available for this artifact")
+			default:
+				respondWithError(w, http.StatusInternalServerError, "Error generating thumbnail")
+			}
+			return
+		}
+
+		w.Header().Set("Content-Type", info.ContentType)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("ETag", fmt.Sprintf(`"%s-%s-%d"`, artifactID, thumbnailVariant(size), info.UploadedAt.Unix()))
+		w.Header().Set("Cache-Control", "private, max-age=86400")
+		http.ServeContent(w, r, "", info.UploadedAt, bytes.NewReader(content))
+	}
+}
+
+// openThumbnail returns a stored thumbnail. When it is missing, every size is rendered
+// from the artifact and stored.
+func openThumbnail(ctx context.Context, store artifactstore.Store, artifactID string, size int) ([]byte, artifactstore.Info, error) {
+	stream, info, err := store.OpenVariant(ctx, artifactID, thumbnailVariant(size))
+	if err == nil {
+		defer stream.Close()
+		content, err := io.ReadAll(stream)
+		return content, info, err
+	}
+	if err != artifactstore.ErrNotFound {
+		return nil, info, err
+	}
+
+	original, info, err := store.Open(ctx, artifactID)
+	if err != nil {
+		return nil, info, err
+	}
+	defer original.Close()
+	if !thumbnail.Supported(info.ContentType) {
+		return nil, info, thumbnail.ErrUnsupported
+	}
+	content, err := io.ReadAll(io.LimitReader(original, maxArtifactSize+1))
+	if err != nil {
+		return nil, info, err
+	}
+
+	thumbnails, err := renderThumbnails(ctx, store, info, content)
+	if thumbnails == nil {
+		return nil, info, err
+	}
+	if err != nil {
+		log.Printf("Storing thumbnails of artifact %s failed: %v", artifactID, err)
+	}
+	for _, thumb := range thumbnails {
+		if thumb.Size == size {
+			return thumb.Content, artifactstore.Info{
+				ID:          info.ID,
+				ContentType: thumb.ContentType,
+				Size:        int64(len(thumb.Content)),
+				UploadedAt:  time.Now().UTC(),
+			}, nil
+		}
+	}
+	return nil, info, artifactstore.ErrNotFound
+}
+
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,30 @@
+package api
+
+import (
+	"net/http"
+
+	"VCCwebsite/internal/oAuth"
+)
+
+// callerIdentity returns the email (or subject) of the authenticated caller,
+// or an empty string when authentication is disabled.
+func callerIdentity(r *http.Request) string {
+	claims, ok := oAuth.GetClaimsFromContext(r.Context())
+	if !ok || claims == nil {
+		return ""
+	}
+	if claims.Email != "" {
+		return claims.Email
+	}
+	return claims.Subject
+}
+
+// callerHasAnyRole reports whether the caller holds one of the given roles.
+// Requests without role information (authentication disabled) are not restricted.
+func callerHasAnyRole(r *http.Request, allowed ...oAuth.Role) bool {
+	roles, ok := oAuth.GetRolesFromContext(r.Context())
+	if !ok {
+		return true
+	}
+	return oAuth.HasAnyRole(roles, allowed...)
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// documentETag derives the entity tag of a script from its current version number
+func documentETag(version int) string {
+	return fmt.Sprintf(`"v%d"`, version)
+}
+
+// currentVersionNumber returns the current version number of a document: the
+// highest of its stored versions and its version counter, or 0 when the document
+// has never been versioned
+func currentVersionNumber(ctx context.Context, versionsCollection *mongo.Collection, docID primitive.ObjectID) (int, error) {
+	stored, err := latestStoredVersion(ctx, versionsCollection, docID)
+	if err != nil {
+		return 0, err
+	}
+
+	var counter versionCounter
+	err = versionsCollection.Database().Collection(versionCountersCollection).
+		FindOne(ctx, bson.M{"_id": docID}).Decode(&counter)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return 0, err
+	}
+	if counter.Seq > stored {
+		return counter.Seq, nil
+	}
+	return stored, nil
+}
+
+// latestStoredVersion returns the highest version number in scripts_versions for a document
+func latestStoredVersion(ctx context.Context, versionsCollection *mongo.Collection, docID primitive.ObjectID) (int, error) {
+	var latest DocumentVersion
+	opts := options.FindOne().
+		SetSort(bson.D{{Key: "version_number", Value: -1}}).
+		SetProjection(bson.M{"version_number": 1})
+	err := versionsCollection.FindOne(ctx, bson.M{"document_id": docID}, opts).Decode(&latest)
+	if err == mongo.ErrNoDocuments {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return latest.VersionNumber, nil
+}
+
+// checkIfMatch enforces the If-Match precondition for a document at version current.
+// It writes 428 when the header is missing and 412 with the current version when it
+// is stale, returning false in both cases.
+func checkIfMatch(w http.ResponseWriter, r *http.Request, current int) bool {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" {
+		respondWithError(w, http.StatusPreconditionRequired, "If-Match header is required")
+		return false
+	}
+
+	etag := documentETag(current)
+	if header == "*" {
+		return true
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag {
+			return true
+		}
+	}
+
+	respondWithPreconditionFailed(w, current)
+	return false
+}
+
+// respondWithPreconditionFailed reports that the client's copy of a document is stale
+func respondWithPreconditionFailed(w http.ResponseWriter, current int) {
+	w.Header().Set("ETag", documentETag(current))
+	respondWithJSON(w, http.StatusPreconditionFailed, map[string]interface{}{
+		"error":           "Document has been modified since it was retrieved",
+		"current_version": current,
+		"etag":            documentETag(current),
+	})
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"VCCwebsite/internal/jsondiff"
+	"VCCwebsite/internal/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// documentDiff is the response of the diff endpoint
+type documentDiff struct {
+	ID      string            `json:"id"`
+	From    string            `json:"from"`
+	To      string            `json:"to"`
+	Changes []jsondiff.Change `json:"changes"`
+}
+
+// HandleGetDocumentDiff compares two states of a document field by field
+// GET /api/document/diff?id=xxx&from=2&to=5
+// GET /api/document/diff?id=xxx&from=2            (to defaults to the live document)
+// GET /api/document/diff?id=xxx&from=2&to=current
+func HandleGetDocumentDiff(w http.ResponseWriter, r *http.Request, collection *mongo.Collection, versionsCollection *mongo.Collection) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	docID := r.URL.Query().Get("id")
+	if docID == "" {
+		respondWithError(w, http.StatusBadRequest, "Document ID is required")
+		return
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(docID)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid document ID format")
+		return
+	}
+
+	from := r.URL.Query().Get("from")
+	if from == "" {
+		respondWithError(w, http.StatusBadRequest, "From version is required")
+		return
+	}
+	to := r.URL.Query().Get("to")
+	if to == "" {
+		to = "current"
+	}
+
+	left, ok := loadDocumentState(ctx, w, collection, versionsCollection, objectID, from)
+	if !ok {
+		return
+	}
+	right, ok := loadDocumentState(ctx, w, collection, versionsCollection, objectID, to)
+	if !ok {
+		return
+	}
+
+	changes, err := jsondiff.Diff(left, right)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error comparing versions")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, documentDiff{
+		ID:      docID,
+		From:    from,
+		To:      to,
+		Changes: changes,
+	})
+}
+
+// loadDocumentState returns the live document for "current" or the stored version
+// with the given number, writing the error response on failure
+func loadDocumentState(ctx context.Context, w http.ResponseWriter, collection *mongo.Collection, versionsCollection *mongo.Collection, docID primitive.ObjectID, spec string) (scripts.StandardizedScript, bool) {
+	if spec == "current" || spec == "live" {
+		var doc scripts.StandardizedScript
+		err := collection.FindOne(ctx, liveFilter(docID)).Decode(&doc)
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				respondWithError(w, http.StatusNotFound, "Document not found")
+				return doc, false
+			}
+			respondWithError(w, http.StatusInternalServerError, "Error retrieving document")
+			return doc, false
+		}
+		return doc, true
+	}
+
+	versionNum, err := strconv.Atoi(spec)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid version number: "+spec)
+		return scripts.StandardizedScript{}, false
+	}
+
+	var version DocumentVersion
+	err = versionsCollection.FindOne(ctx, bson.M{
+		"document_id":    docID,
+		"version_number": versionNum,
+	}).Decode(&version)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			respondWithError(w, http.StatusNotFound, "Version "+spec+" not found")
+			return version.Document, false
+		}
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving version")
+		return version.Document, false
+	}
+	return version.Document, true
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// documentFacets are the admin fields counted by GET /api/document/facets, keyed by
+// the facet name, which is also the query parameter that filters on it
+var documentFacets = []struct {
+	Name  string
+	Field string
+}{
+	{"class", "admin.class"},
+	{"learner_level", "admin.learner_level"},
+	{"academic_year", "admin.academic_year"},
+	{"medical_event", "admin.medical_event"},
+	{"diagnosis", "admin.diagnosis"},
+}
+
+// facetBucket is the number of scripts with one value of a facet
+type facetBucket struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// HandleGetDocumentFacets counts the scripts per value of each facet
+// GET /api/document/facets
+// GET /api/document/facets?class=xxx&q=xxx - any list filter of /api/document applies
+//
+// Each facet is counted with every active filter except its own, so a sidebar can show
+// the alternatives to the selected value. "total" counts the scripts matching all filters.
+func HandleGetDocumentFacets(w http.ResponseWriter, r *http.Request, collection *mongo.Collection) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter, err := buildFilterFromQuery(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if q := strings.TrimSpace(r.URL.Query().Get("q")); q != "" {
+		filter["$text"] = bson.M{"$search": q}
+	}
+
+	// Split the filter into the part every facet shares and the per-facet conditions
+	facetFilters := bson.M{}
+	common := bson.M{}
+	for key, value := range filter {
+		common[key] = value
+	}
+	for _, facet := range documentFacets {
+		if value, ok := filter[facet.Field]; ok {
+			facetFilters[facet.Field] = value
+			delete(common, facet.Field)
+		}
+	}
+
+	stages := bson.M{
+		"total": bson.A{
+			bson.M{"$match": facetFilters},
+			bson.M{"$count": "count"},
+		},
+	}
+	for _, facet := range documentFacets {
+		others := bson.M{}
+		for key, value := range facetFilters {
+			if key != facet.Field {
+				others[key] = value
+			}
+		}
+		stages[facet.Name] = bson.A{
+			bson.M{"$match": others},
+			bson.M{"$group": bson.M{"_id": "$" + facet.Field, "count": bson.M{"$sum": 1}}},
+			bson.M{"$match": bson.M{"_id": bson.M{"$nin": bson.A{nil, ""}}}},
+			bson.M{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}},
+		}
+	}
+
+	cursor, err := collection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: common}},
+		{{Key: "$facet", Value: stages}},
+	})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error counting facets")
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var results []map[string][]struct {
+		ID    interface{} `bson:"_id"`
+		Count int         `bson:"count"`
+	}
+	if err := cursor.All(ctx, &results); err != nil || len(results) == 0 {
+		respondWithError(w, http.StatusInternalServerError, "Error decoding facets")
+		return
+	}
+
+	response := map[string]interface{}{"total": 0}
+	if total := results[0]["total"]; len(total) > 0 {
+		response["total"] = total[0].Count
+	}
+	for _, facet := range documentFacets {
+		buckets := []facetBucket{}
+		for _, group := range results[0][facet.Name] {
+			buckets = append(buckets, facetBucket{Value: fmt.Sprint(group.ID), Count: group.Count})
+		}
+		response[facet.Name] = buckets
+	}
+
+	respondWithJSON(w, http.StatusOK, response)
+}
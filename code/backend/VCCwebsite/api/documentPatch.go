@@ -0,0 +1,282 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"time"
+
+	"VCCwebsite/internal/jsonpatch"
+	"VCCwebsite/internal/model"
+	"VCCwebsite/internal/validation"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	maxPatchSize = 1 << 20 // 1MB
+
+	mergePatchContentType = "application/merge-patch+json"
+	jsonPatchContentType  = "application/json-patch+json"
+)
+
+// applyPatchRequest applies the PATCH body of r to original and decodes the result
+// into target. Merge patches (RFC 7396) are accepted as application/merge-patch+json
+// or application/json, JSON Patches (RFC 6902) as application/json-patch+json.
+// Unknown fields and wrongly typed values in the result are rejected.
+func applyPatchRequest(r *http.Request, original interface{}, target interface{}) error {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		mediaType = ""
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxPatchSize+1))
+	if err != nil {
+		return &statusError{http.StatusBadRequest, "Invalid request payload"}
+	}
+	if len(body) > maxPatchSize {
+		return &statusError{http.StatusRequestEntityTooLarge, "Patch exceeds 1MB limit"}
+	}
+
+	doc, err := json.Marshal(original)
+	if err != nil {
+		return err
+	}
+
+	var patched []byte
+	switch mediaType {
+	case mergePatchContentType, "application/json":
+		patched, err = jsonpatch.MergePatch(doc, body)
+	case jsonPatchContentType:
+		var ops []jsonpatch.Operation
+		if ops, err = jsonpatch.DecodePatch(body); err == nil {
+			patched, err = jsonpatch.Apply(doc, ops)
+		}
+	default:
+		return &statusError{http.StatusUnsupportedMediaType, "PATCH requires Content-Type " + mergePatchContentType + " or " + jsonPatchContentType}
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, jsonpatch.ErrTestFailed):
+			return &statusError{http.StatusConflict, err.Error()}
+		case errors.Is(err, jsonpatch.ErrInvalidPatch):
+			return &statusError{http.StatusBadRequest, err.Error()}
+		default:
+			return &statusError{http.StatusUnprocessableEntity, err.Error()}
+		}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(patched))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(target); err != nil {
+		return &statusError{http.StatusUnprocessableEntity, "Patched document is invalid: " + err.Error()}
+	}
+	return nil
+}
+
+// patchUpdate builds the update that turns the stored form of original into patched:
+// every top-level field of patched is $set and fields that disappeared are $unset.
+func patchUpdate(original, patched interface{}) (bson.M, error) {
+	before, err := topLevelFields(original)
+	if err != nil {
+		return nil, err
+	}
+	after, err := topLevelFields(patched)
+	if err != nil {
+		return nil, err
+	}
+
+	unset := bson.M{}
+	for key := range before {
+		if _, ok := after[key]; !ok {
+			unset[key] = ""
+		}
+	}
+
+	update := bson.M{"$set": after}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	return update, nil
+}
+
+func topLevelFields(v interface{}) (bson.M, error) {
+	data, err := bson.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	fields := bson.M{}
+	if err := bson.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	delete(fields, "_id")
+	return fields, nil
+}
+
+// handlePatchDocument applies a merge patch or JSON Patch to a stored script
+// PATCH /api/document?id=xxx (requires If-Match)
+func handlePatchDocument(w http.ResponseWriter, r *http.Request, collection *mongo.Collection, versionsCollection *mongo.Collection) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	docID := r.URL.Query().Get("id")
+	if docID == "" {
+		respondWithError(w, http.StatusBadRequest, "Document ID is required")
+		return
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(docID)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid document ID format")
+		return
+	}
+
+	var stored scripts.StandardizedScript
+	err = collection.FindOne(ctx, liveFilter(objectID)).Decode(&stored)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			respondWithError(w, http.StatusNotFound, "Document not found")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving document")
+		return
+	}
+
+	current, err := currentVersionNumber(ctx, versionsCollection, objectID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving document version")
+		return
+	}
+	if !checkIfMatch(w, r, current) {
+		return
+	}
+
+	var patched scripts.StandardizedScript
+	if err := applyPatchRequest(r, stored, &patched); err != nil {
+		respondWithStatusError(w, err, "Error applying patch")
+		return
+	}
+	if err := validation.Script(&patched); err != nil {
+		respondWithValidationError(w, err)
+		return
+	}
+	patched.CreatedAt = stored.CreatedAt
+	patched.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+
+	update, err := patchUpdate(stored, patched)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error processing update")
+		return
+	}
+
+	createdBy := r.URL.Query().Get("created_by")
+	if by := callerIdentity(r); by != "" {
+		createdBy = by
+	}
+	savedVersion, err := saveVersion(ctx, collection, versionsCollection, objectID, r.URL.Query().Get("change_note"), createdBy, current)
+	if err == errVersionConflict {
+		respondWithStaleVersion(ctx, w, versionsCollection, objectID)
+		return
+	}
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error saving version before update")
+		return
+	}
+
+	result, err := collection.UpdateOne(ctx, liveFilter(objectID), update)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error updating document")
+		return
+	}
+	if result.MatchedCount == 0 {
+		respondWithError(w, http.StatusNotFound, "Document not found")
+		return
+	}
+
+	var rawDoc bson.M
+	err = collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&rawDoc)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving updated document")
+		return
+	}
+
+	w.Header().Set("ETag", documentETag(savedVersion))
+	setVitalsWarnings(w, &patched)
+	respondWithJSON(w, http.StatusOK, convertToDocumentWithID(rawDoc))
+}
+
+// handlePatchScriptRequest applies a merge patch or JSON Patch to a script request.
+// Workflow fields (status, history, approved_script_id) cannot be patched.
+// PATCH /api/script-request?id=xxx
+func handlePatchScriptRequest(w http.ResponseWriter, r *http.Request, collection *mongo.Collection) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objectID, ok := parseScriptRequestID(w, r)
+	if !ok {
+		return
+	}
+
+	var stored scripts.ScriptRequest
+	err := collection.FindOne(ctx, liveFilter(objectID)).Decode(&stored)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			respondWithError(w, http.StatusNotFound, "Script request not found")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving script request")
+		return
+	}
+
+	var patched scripts.ScriptRequest
+	if err := applyPatchRequest(r, stored, &patched); err != nil {
+		respondWithStatusError(w, err, "Error applying patch")
+		return
+	}
+	if err := validation.ScriptRequest(&patched); err != nil {
+		respondWithValidationError(w, err)
+		return
+	}
+
+	if patched.Status != stored.Status ||
+		patched.ApprovedScriptID != stored.ApprovedScriptID ||
+		!reflect.DeepEqual(patched.History, stored.History) {
+		respondWithError(w, http.StatusConflict, "Status changes must go through the /api/script-request/{action} endpoints")
+		return
+	}
+	patched.CreatedAt = stored.CreatedAt
+	patched.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+
+	update, err := patchUpdate(stored, patched)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error processing update")
+		return
+	}
+
+	// Guard against a concurrent workflow transition between read and write
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": objectID, "status": requestStatusFilter(stored.Status), deletedAtField: notTrashed}, update)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error updating script request")
+		return
+	}
+	if result.MatchedCount == 0 {
+		respondWithError(w, http.StatusConflict, "Script request changed concurrently, reload and retry")
+		return
+	}
+
+	var rawDoc bson.M
+	err = collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&rawDoc)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving updated script request")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, convertToScriptRequestWithID(rawDoc))
+}
@@ -2,15 +2,19 @@ package api
 
 import (
 	"VCCwebsite/internal/model"
+	"VCCwebsite/internal/validation"
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // DocumentHandler handles all document-related REST API endpoints
@@ -59,6 +63,36 @@ func DocumentHandler(client *mongo.Client) http.HandlerFunc {
 			return
 		}
 
+		// Check for /api/document/diff (compare two versions)
+		if strings.HasSuffix(path, "/diff") {
+			if r.Method == http.MethodGet {
+				HandleGetDocumentDiff(w, r, collection, versionsCollection)
+				return
+			}
+			respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		// Check for /api/document/facets
+		if strings.HasSuffix(path, "/facets") {
+			if r.Method == http.MethodGet {
+				HandleGetDocumentFacets(w, r, collection)
+				return
+			}
+			respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		// Check for /api/document/undelete (take a document out of the trash)
+		if strings.HasSuffix(path, "/undelete") {
+			if r.Method == http.MethodPost {
+				handleUndeleteDocument(w, r, collection)
+				return
+			}
+			respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
 		// Check for /api/document/medications
 		if strings.HasSuffix(path, "/medications") {
 			if r.Method == http.MethodGet {
@@ -82,14 +116,16 @@ func DocumentHandler(client *mongo.Client) http.HandlerFunc {
 		// Original routing for base /api/document
 		switch r.Method {
 		case http.MethodGet:
-			handleGetDocuments(w, r, collection)
+			handleGetDocuments(w, r, collection, versionsCollection)
 		case http.MethodPost:
 			handleCreateDocument(w, r, collection)
 		case http.MethodPut:
 			// Use the new versioning update handler
 			handleUpdateDocumentWithVersioning(w, r, collection, versionsCollection)
+		case http.MethodPatch:
+			handlePatchDocument(w, r, collection, versionsCollection)
 		case http.MethodDelete:
-			handleDeleteDocument(w, r, collection)
+			handleDeleteDocument(w, r, collection, versionsCollection)
 		default:
 			respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
 		}
@@ -102,16 +138,26 @@ type DocumentWithID struct {
 
 // handleGetDocuments retrieves documents
 // GET /api/document - get all documents
-// GET /api/document?id=xxx - get specific document by ID
+// GET /api/document?id=xxx - get specific document by ID (sets ETag)
 // GET /api/document?title=xxx - search by admin title
 // GET /api/document?author=xxx - search by admin author
 // GET /api/document?diagnosis=xxx - search by admin diagnosis
 // GET /api/document?learner_level=xxx - search by learner level
 // GET /api/document?patient_name=xxx - search by patient name
-func handleGetDocuments(w http.ResponseWriter, r *http.Request, collection *mongo.Collection) {
+// GET /api/document?q=xxx - full-text search, ranked, with highlighted snippets
+// GET /api/document?view=summary or ?fields=a.b,c - only the listed paths (any of the above)
+// List requests also take page=, limit= and sort= (see parseListParams) and report the
+// number of matching documents in X-Total-Count.
+func handleGetDocuments(w http.ResponseWriter, r *http.Request, collection *mongo.Collection, versionsCollection *mongo.Collection) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	projection, err := documentProjection(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	// Check if requesting a specific document by ID
 	docID := r.URL.Query().Get("id")
 	if docID != "" {
@@ -121,8 +167,13 @@ func handleGetDocuments(w http.ResponseWriter, r *http.Request, collection *mong
 			return
 		}
 
+		opts := options.FindOne()
+		if projection != nil {
+			opts.SetProjection(projection)
+		}
+
 		var rawDoc bson.M
-		err = collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&rawDoc)
+		err = collection.FindOne(ctx, liveFilter(objectID), opts).Decode(&rawDoc)
 		if err != nil {
 			if err == mongo.ErrNoDocuments {
 				respondWithError(w, http.StatusNotFound, "Document not found")
@@ -132,17 +183,55 @@ func handleGetDocuments(w http.ResponseWriter, r *http.Request, collection *mong
 			return
 		}
 
+		version, err := currentVersionNumber(ctx, versionsCollection, objectID)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error retrieving document version")
+			return
+		}
+		w.Header().Set("ETag", documentETag(version))
+
+		if projection != nil {
+			respondWithJSON(w, http.StatusOK, projectedDocument(rawDoc))
+			return
+		}
+
 		// Convert to DocumentWithID
 		doc := convertToDocumentWithID(rawDoc)
 		respondWithJSON(w, http.StatusOK, doc)
 		return
 	}
 
+	params, err := parseListParams(r, documentSortFields)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	// Build filter based on query parameters
-	filter := buildFilterFromQuery(r)
+	filter, err := buildFilterFromQuery(r)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if q := strings.TrimSpace(r.URL.Query().Get("q")); q != "" {
+		searchDocuments(ctx, w, collection, params, filter, projection, q)
+		return
+	}
+
+	total, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error counting documents")
+		return
+	}
+
+	opts := params.findOptions()
+	if projection != nil {
+		opts.SetProjection(projection)
+	}
 
 	// Get documents with filter
-	cursor, err := collection.Find(ctx, filter)
+	cursor, err := collection.Find(ctx, filter, opts)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Error retrieving documents")
 		return
@@ -155,6 +244,17 @@ func handleGetDocuments(w http.ResponseWriter, r *http.Request, collection *mong
 		return
 	}
 
+	setListHeaders(w, params, total)
+
+	if projection != nil {
+		projected := make([]bson.M, 0, len(rawDocs))
+		for _, rawDoc := range rawDocs {
+			projected = append(projected, projectedDocument(rawDoc))
+		}
+		respondWithJSON(w, http.StatusOK, projected)
+		return
+	}
+
 	// Convert to DocumentWithID slice
 	documents := make([]DocumentWithID, 0, len(rawDocs))
 	for _, rawDoc := range rawDocs {
@@ -181,8 +281,13 @@ func handleCreateDocument(w http.ResponseWriter, r *http.Request, collection *mo
 		return
 	}
 
-	// Validate required fields based on your struct
-	// Add validation as needed for your specific use case
+	if err := validation.Script(&doc); err != nil {
+		respondWithValidationError(w, err)
+		return
+	}
+
+	doc.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	doc.UpdatedAt = doc.CreatedAt
 
 	result, err := collection.InsertOne(ctx, doc)
 	if err != nil {
@@ -200,6 +305,7 @@ func handleCreateDocument(w http.ResponseWriter, r *http.Request, collection *mo
 
 	// Convert to DocumentWithID
 	docWithID := convertToDocumentWithID(rawDoc)
+	setVitalsWarnings(w, &doc)
 	respondWithJSON(w, http.StatusCreated, docWithID)
 }
 
@@ -285,9 +391,12 @@ func handleUpdateDocument(w http.ResponseWriter, r *http.Request, collection *mo
 	respondWithJSON(w, http.StatusOK, updatedDoc)
 }
 
-// handleDeleteDocument deletes a document
-// DELETE /api/document?id=xxx
-func handleDeleteDocument(w http.ResponseWriter, r *http.Request, collection *mongo.Collection) {
+// handleDeleteDocument moves a document to the trash, from which it can be undeleted
+// until the retention period runs out (see TrashHandler). Like an edit, the move saves
+// a version claiming the If-Match version, so an edit committed meanwhile makes it
+// fail with 412 instead of being trashed unseen.
+// DELETE /api/document?id=xxx with If-Match, like edits
+func handleDeleteDocument(w http.ResponseWriter, r *http.Request, collection *mongo.Collection, versionsCollection *mongo.Collection) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
@@ -303,20 +412,64 @@ func handleDeleteDocument(w http.ResponseWriter, r *http.Request, collection *mo
 		return
 	}
 
-	result, err := collection.DeleteOne(ctx, bson.M{"_id": objectID})
+	// Deleting needs the same up-to-date copy as editing
+	if err := collection.FindOne(ctx, liveFilter(objectID)).Err(); err != nil {
+		if err == mongo.ErrNoDocuments {
+			respondWithError(w, http.StatusNotFound, "Document not found")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving document")
+		return
+	}
+	current, err := currentVersionNumber(ctx, versionsCollection, objectID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving document version")
+		return
+	}
+	if !checkIfMatch(w, r, current) {
+		return
+	}
+
+	// Trashing is reversible, so references only inform; the purge applies them
+	refs, err := findReferences(ctx, collection.Database(), "document", objectID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error checking document references")
+		return
+	}
+
+	by := callerIdentity(r)
+	savedVersion, err := saveVersion(ctx, collection, versionsCollection, objectID, "Moved to trash", by, current)
+	if err == errVersionConflict {
+		respondWithStaleVersion(ctx, w, versionsCollection, objectID)
+		return
+	}
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			respondWithError(w, http.StatusNotFound, "Document not found")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Error saving version before delete")
+		return
+	}
+
+	trashed, err := moveToTrash(ctx, collection, objectID, by)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Error deleting document")
 		return
 	}
 
-	if result.DeletedCount == 0 {
+	if !trashed {
+		// Trashed concurrently; the version saved for this delete is not needed
+		_, _ = versionsCollection.DeleteOne(ctx, bson.M{"document_id": objectID, "version_number": savedVersion})
+		releaseVersionNumber(ctx, versionsCollection, objectID, savedVersion)
 		respondWithError(w, http.StatusNotFound, "Document not found")
 		return
 	}
 
-	respondWithJSON(w, http.StatusOK, map[string]string{
-		"message": "Document deleted successfully",
-		"id":      docID,
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"message":    "Document moved to trash",
+		"id":         docID,
+		"references": refs,
 	})
 }
 
@@ -352,26 +505,40 @@ func convertToDocumentWithID(rawDoc bson.M) DocumentWithID {
 	return doc
 }
 
+// documentSortFields are the sort= names accepted by GET /api/document. name is the
+// title under the name the request and actor lists use.
+var documentSortFields = map[string]string{
+	"name":          "admin.reson_for_visit",
+	"title":         "admin.reson_for_visit",
+	"academic_year": "admin.academic_year",
+	"author":        "admin.author",
+	"diagnosis":     "admin.diagnosis",
+	"learner_level": "admin.learner_level",
+	"patient_name":  "patient.name",
+	"created_at":    "created_at",
+	"updated_at":    "updated_at",
+}
+
 // buildFilterFromQuery builds a MongoDB filter from URL query parameters
-func buildFilterFromQuery(r *http.Request) bson.M {
-	filter := bson.M{}
+func buildFilterFromQuery(r *http.Request) (bson.M, error) {
+	filter := bson.M{deletedAtField: notTrashed}
 	query := r.URL.Query()
 
 	// Admin fields
 	if title := query.Get("title"); title != "" {
-		filter["admin.reason_for_visit"] = bson.M{"$regex": title, "$options": "i"} // case-insensitive search
+		filter["admin.reson_for_visit"] = containsFilter(title)
 	}
 	if chiefConcern := query.Get("chief_concern"); chiefConcern != "" {
-		filter["admin.chief_concern"] = bson.M{"$regex": chiefConcern, "$options": "i"}
+		filter["admin.chief_concern"] = containsFilter(chiefConcern)
 	}
 	if diagnosis := query.Get("diagnosis"); diagnosis != "" {
-		filter["admin.diagnosis"] = bson.M{"$regex": diagnosis, "$options": "i"}
+		filter["admin.diagnosis"] = containsFilter(diagnosis)
 	}
 	if class := query.Get("class"); class != "" {
 		filter["admin.class"] = class
 	}
 	if medicalEvent := query.Get("medical_event"); medicalEvent != "" {
-		filter["admin.medical_event"] = bson.M{"$regex": medicalEvent, "$options": "i"}
+		filter["admin.medical_event"] = containsFilter(medicalEvent)
 	}
 	if learnerLevel := query.Get("learner_level"); learnerLevel != "" {
 		filter["admin.learner_level"] = learnerLevel
@@ -380,36 +547,40 @@ func buildFilterFromQuery(r *http.Request) bson.M {
 		filter["admin.academic_year"] = academicYear
 	}
 	if author := query.Get("author"); author != "" {
-		filter["admin.author"] = bson.M{"$regex": author, "$options": "i"}
+		filter["admin.author"] = containsFilter(author)
 	}
 
 	// Patient fields
 	if patientName := query.Get("patient_name"); patientName != "" {
-		filter["patient.name"] = bson.M{"$regex": patientName, "$options": "i"}
+		filter["patient.name"] = containsFilter(patientName)
 	}
-	if age := query.Get("patient_age"); age != "" {
+	if raw := query.Get("patient_age"); raw != "" {
+		age, err := strconv.Atoi(raw)
+		if err != nil || age < 0 {
+			return nil, fmt.Errorf("patient_age must be a non-negative integer")
+		}
 		filter["patient.age"] = age
 	}
 	if gender := query.Get("patient_gender"); gender != "" {
-		filter["patient.gender"] = bson.M{"$regex": gender, "$options": "i"}
+		filter["patient.gender"] = containsFilter(gender)
 	}
 
 	// SP fields
 	if spName := query.Get("sp_name"); spName != "" {
-		filter["sp.sp_name"] = bson.M{"$regex": spName, "$options": "i"}
+		filter["sp.sp_name"] = containsFilter(spName)
 	}
 
 	// Search query - searches across multiple fields
 	if search := query.Get("search"); search != "" {
 		filter["$or"] = []bson.M{
-			{"admin.reason_for_visit": bson.M{"$regex": search, "$options": "i"}},
-			{"admin.chief_concern": bson.M{"$regex": search, "$options": "i"}},
-			{"admin.diagnosis": bson.M{"$regex": search, "$options": "i"}},
-			{"admin.author": bson.M{"$regex": search, "$options": "i"}},
-			{"patient.name": bson.M{"$regex": search, "$options": "i"}},
-			{"sp.sp_name": bson.M{"$regex": search, "$options": "i"}},
+			{"admin.reson_for_visit": containsFilter(search)},
+			{"admin.chief_concern": containsFilter(search)},
+			{"admin.diagnosis": containsFilter(search)},
+			{"admin.author": containsFilter(search)},
+			{"patient.name": containsFilter(search)},
+			{"sp.sp_name": containsFilter(search)},
 		}
 	}
 
-	return filter
+	return filter, nil
 }
@@ -3,8 +3,10 @@ package api
 
 import (
 	"VCCwebsite/internal/model"
+	"VCCwebsite/internal/validation"
 	"context"
 	"encoding/json"
+	"log"
 	"net/http"
 	"strconv"
 	"time"
@@ -26,42 +28,48 @@ type DocumentVersion struct {
 	ChangeNote    string                     `bson:"change_note,omitempty" json:"change_note,omitempty"`
 }
 
-// saveVersion saves the current document as a new version
-func saveVersion(ctx context.Context, collection *mongo.Collection, versionsCollection *mongo.Collection, docID primitive.ObjectID, changeNote string, createdBy string) error {
+// saveVersion saves the current document as a new version and returns its version number.
+// When expected is >= 0 the version is only written if expected is still the latest
+// version number, otherwise errVersionConflict is returned.
+func saveVersion(ctx context.Context, collection *mongo.Collection, versionsCollection *mongo.Collection, docID primitive.ObjectID, changeNote string, createdBy string, expected int) (int, error) {
 	// Get the current document
 	var currentDoc scripts.StandardizedScript
-	err := collection.FindOne(ctx, bson.M{"_id": docID}).Decode(&currentDoc)
+	err := collection.FindOne(ctx, liveFilter(docID)).Decode(&currentDoc)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	// Get the latest version number
-	var latestVersion DocumentVersion
-	opts := options.FindOne().SetSort(bson.D{{Key: "version_number", Value: -1}})
-	err = versionsCollection.FindOne(ctx, bson.M{"document_id": docID}, opts).Decode(&latestVersion)
+	for attempt := 0; attempt < maxVersionAttempts; attempt++ {
+		versionNumber, err := allocateVersionNumber(ctx, versionsCollection, docID, expected)
+		if err != nil {
+			return 0, err
+		}
 
-	versionNumber := 1
-	if err == nil {
-		// Found a previous version, increment
-		versionNumber = latestVersion.VersionNumber + 1
-	} else if err != mongo.ErrNoDocuments {
-		// Real error (not just "no documents found")
-		return err
-	}
+		// Create the version document
+		version := DocumentVersion{
+			DocumentID:    docID,
+			VersionNumber: versionNumber,
+			Document:      currentDoc,
+			CreatedAt:     time.Now(),
+			CreatedBy:     createdBy,
+			ChangeNote:    changeNote,
+		}
 
-	// Create the version document
-	version := DocumentVersion{
-		DocumentID:    docID,
-		VersionNumber: versionNumber,
-		Document:      currentDoc,
-		CreatedAt:     time.Now(),
-		CreatedBy:     createdBy,
-		ChangeNote:    changeNote,
+		// Insert the version
+		_, err = versionsCollection.InsertOne(ctx, version)
+		if err == nil {
+			return versionNumber, nil
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			releaseVersionNumber(ctx, versionsCollection, docID, versionNumber)
+			return 0, err
+		}
+		// The counter was behind the stored versions; it is resynced on the next attempt
+		if expected >= 0 {
+			return 0, errVersionConflict
+		}
 	}
-
-	// Insert the version
-	_, err = versionsCollection.InsertOne(ctx, version)
-	return err
+	return 0, errVersionConflict
 }
 
 // HandleGetVersionHistory retrieves all versions of a document
@@ -153,7 +161,7 @@ func HandleGetSpecificVersion(w http.ResponseWriter, r *http.Request, versionsCo
 }
 
 // HandleRestoreVersion restores a document to a specific version
-// POST /api/document/restore?id=xxx&version=2
+// POST /api/document/restore?id=xxx&version=2 (requires If-Match)
 func HandleRestoreVersion(w http.ResponseWriter, r *http.Request, collection *mongo.Collection, versionsCollection *mongo.Collection) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -198,15 +206,33 @@ func HandleRestoreVersion(w http.ResponseWriter, r *http.Request, collection *mo
 		return
 	}
 
+	current, err := currentVersionNumber(ctx, versionsCollection, objectID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving document version")
+		return
+	}
+	if !checkIfMatch(w, r, current) {
+		return
+	}
+
 	// Save current state as a version before restoring
-	err = saveVersion(ctx, collection, versionsCollection, objectID, "Before restore to version "+versionStr, "system")
+	_, err = saveVersion(ctx, collection, versionsCollection, objectID, "Before restore to version "+versionStr, restoredBy(r), current)
+	if err == errVersionConflict {
+		respondWithStaleVersion(ctx, w, versionsCollection, objectID)
+		return
+	}
+	if err == mongo.ErrNoDocuments {
+		respondWithError(w, http.StatusNotFound, "Document not found")
+		return
+	}
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Error saving current version before restore")
 		return
 	}
 
 	// Restore the document by replacing it with the version's document
-	result, err := collection.ReplaceOne(ctx, bson.M{"_id": objectID}, version.Document)
+	version.Document.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+	result, err := collection.ReplaceOne(ctx, liveFilter(objectID), version.Document)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Error restoring document")
 		return
@@ -218,10 +244,13 @@ func HandleRestoreVersion(w http.ResponseWriter, r *http.Request, collection *mo
 	}
 
 	// Save the restored state as a new version
-	err = saveVersion(ctx, collection, versionsCollection, objectID, "Restored to version "+versionStr, "system")
+	restoredVersion, err := saveVersion(ctx, collection, versionsCollection, objectID, "Restored to version "+versionStr, restoredBy(r), -1)
 	if err != nil {
 		// Document was restored but we couldn't save the version - log but don't fail
 		// In production, you might want to handle this differently
+		log.Printf("restore of %s: saving restored version failed: %v", docID, err)
+	} else {
+		w.Header().Set("ETag", documentETag(restoredVersion))
 	}
 
 	respondWithJSON(w, http.StatusOK, map[string]string{
@@ -230,8 +259,17 @@ func HandleRestoreVersion(w http.ResponseWriter, r *http.Request, collection *mo
 	})
 }
 
+// restoredBy names the caller in version entries written by a restore
+func restoredBy(r *http.Request) string {
+	if by := callerIdentity(r); by != "" {
+		return by
+	}
+	return "system"
+}
+
 // Modified handleUpdateDocument to save versions
 // Replace your existing handleUpdateDocument with this version
+// PUT /api/document?id=xxx (requires If-Match)
 func handleUpdateDocumentWithVersioning(w http.ResponseWriter, r *http.Request, collection *mongo.Collection, versionsCollection *mongo.Collection) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -248,17 +286,7 @@ func handleUpdateDocumentWithVersioning(w http.ResponseWriter, r *http.Request,
 		return
 	}
 
-	// Save current version before updating
-	changeNote := r.URL.Query().Get("change_note")
-	createdBy := r.URL.Query().Get("created_by")
-
-	err = saveVersion(ctx, collection, versionsCollection, objectID, changeNote, createdBy)
-	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Error saving version before update")
-		return
-	}
-
-	// Continue with normal update logic
+	// Decode the update before touching the version history
 	var updateDoc scripts.StandardizedScript
 	if err := json.NewDecoder(r.Body).Decode(&updateDoc); err != nil {
 		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
@@ -278,6 +306,7 @@ func handleUpdateDocumentWithVersioning(w http.ResponseWriter, r *http.Request,
 	}
 
 	delete(updateFields, "_id")
+	delete(updateFields, "created_at")
 
 	for key, value := range updateFields {
 		if value == nil || value == "" {
@@ -290,11 +319,63 @@ func handleUpdateDocumentWithVersioning(w http.ResponseWriter, r *http.Request,
 		return
 	}
 
+	// Validate the document as it will look after the top-level fields are replaced
+	var merged scripts.StandardizedScript
+	err = collection.FindOne(ctx, liveFilter(objectID)).Decode(&merged)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			respondWithError(w, http.StatusNotFound, "Document not found")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving document")
+		return
+	}
+	mergedBytes, _ := json.Marshal(updateFields)
+	if err := json.Unmarshal(mergedBytes, &merged); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error processing update")
+		return
+	}
+	if err := validation.Script(&merged); err != nil {
+		respondWithValidationError(w, err)
+		return
+	}
+	updateFields["updated_at"] = time.Now().UTC().Format(time.RFC3339)
+
+	current, err := currentVersionNumber(ctx, versionsCollection, objectID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving document version")
+		return
+	}
+	if !checkIfMatch(w, r, current) {
+		return
+	}
+
+	// Save current version before updating
+	changeNote := r.URL.Query().Get("change_note")
+	createdBy := r.URL.Query().Get("created_by")
+	if by := callerIdentity(r); by != "" {
+		createdBy = by
+	}
+
+	savedVersion, err := saveVersion(ctx, collection, versionsCollection, objectID, changeNote, createdBy, current)
+	if err == errVersionConflict {
+		respondWithStaleVersion(ctx, w, versionsCollection, objectID)
+		return
+	}
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			respondWithError(w, http.StatusNotFound, "Document not found")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Error saving version before update")
+		return
+	}
+
 	update := bson.M{
 		"$set": updateFields,
 	}
 
-	result, err := collection.UpdateOne(ctx, bson.M{"_id": objectID}, update)
+	result, err := collection.UpdateOne(ctx, liveFilter(objectID), update)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Error updating document")
 		return
@@ -312,6 +393,8 @@ func handleUpdateDocumentWithVersioning(w http.ResponseWriter, r *http.Request,
 		return
 	}
 
+	w.Header().Set("ETag", documentETag(savedVersion))
+	setVitalsWarnings(w, &merged)
 	updatedDoc := convertToDocumentWithID(rawDoc)
 	respondWithJSON(w, http.StatusOK, updatedDoc)
 }
@@ -0,0 +1,185 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+	"time"
+
+	scripts "VCCwebsite/internal/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// migrationsCollection records the one-shot data migrations that have completed
+const migrationsCollection = "migrations"
+
+// jsonFieldNamesMigration is the migration renaming legacy field names
+const jsonFieldNamesMigration = "json_field_names"
+
+// legacyFieldTargets are the collections written with the driver's default field names,
+// with the path of the model document inside each record ("" for the record itself)
+var legacyFieldTargets = []struct {
+	Collection string
+	Path       string
+	Model      reflect.Type
+}{
+	{"scripts", "", reflect.TypeOf(scripts.StandardizedScript{})},
+	{"script_requests", "", reflect.TypeOf(scripts.ScriptRequest{})},
+	{"scripts_versions", "document", reflect.TypeOf(scripts.StandardizedScript{})},
+}
+
+// MigrateLegacyFieldNames renames the fields of records inserted before the models had
+// bson tags. The driver stored lowercased Go field names (medhist, resonforvisit, ...),
+// while updates built from the JSON form wrote the json names the bson tags now pin.
+// Where both the legacy and the tagged key exist, the tagged key came from a later
+// update and wins.
+// The migration runs once; completion is recorded in the migrations collection.
+func MigrateLegacyFieldNames(ctx context.Context, client *mongo.Client) error {
+	if client == nil {
+		return nil
+	}
+	database := client.Database("vccwebsite")
+	migrations := database.Collection(migrationsCollection)
+
+	err := migrations.FindOne(ctx, bson.M{"_id": jsonFieldNamesMigration}).Err()
+	if err == nil {
+		return nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return err
+	}
+
+	for _, target := range legacyFieldTargets {
+		renamed, err := migrateCollectionFieldNames(ctx, database.Collection(target.Collection), target.Path, target.Model)
+		if err != nil {
+			return fmt.Errorf("%s: %w", target.Collection, err)
+		}
+		if renamed > 0 {
+			log.Printf("Renamed legacy field names in %d %s record(s)", renamed, target.Collection)
+		}
+	}
+
+	_, err = migrations.UpdateOne(ctx,
+		bson.M{"_id": jsonFieldNamesMigration},
+		bson.M{"$set": bson.M{"completed_at": time.Now().UTC()}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+func migrateCollectionFieldNames(ctx context.Context, collection *mongo.Collection, path string, model reflect.Type) (int, error) {
+	cursor, err := collection.Find(ctx, bson.M{})
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	renamed := 0
+	for cursor.Next(ctx) {
+		var record bson.M
+		if err := cursor.Decode(&record); err != nil {
+			return renamed, err
+		}
+
+		doc := record
+		if path != "" {
+			nested, ok := asDocument(record[path])
+			if !ok {
+				continue
+			}
+			doc = nested
+		}
+		if !renameLegacyFields(doc, model) {
+			continue
+		}
+		if path != "" {
+			record[path] = doc
+		}
+
+		if _, err := collection.ReplaceOne(ctx, bson.M{"_id": record["_id"]}, record); err != nil {
+			return renamed, err
+		}
+		renamed++
+	}
+	return renamed, cursor.Err()
+}
+
+// renameLegacyFields moves every field of doc stored under its lowercased Go name to its
+// bson tag name, recursing into nested structs and slices of structs. It reports
+// whether anything changed.
+func renameLegacyFields(doc bson.M, model reflect.Type) bool {
+	changed := false
+	for i := 0; i < model.NumField(); i++ {
+		field := model.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("bson"), ",")
+		if name == "-" {
+			continue
+		}
+		legacy := strings.ToLower(field.Name)
+		if name == "" {
+			name = legacy
+		}
+
+		if name != legacy {
+			if value, ok := doc[legacy]; ok {
+				if _, exists := doc[name]; !exists {
+					doc[name] = value
+				}
+				delete(doc, legacy)
+				changed = true
+			}
+		}
+
+		value, ok := doc[name]
+		if !ok {
+			continue
+		}
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+		switch fieldType.Kind() {
+		case reflect.Struct:
+			if nested, ok := asDocument(value); ok && renameLegacyFields(nested, fieldType) {
+				doc[name] = nested
+				changed = true
+			}
+		case reflect.Slice, reflect.Array:
+			elemType := fieldType.Elem()
+			if elemType.Kind() == reflect.Pointer {
+				elemType = elemType.Elem()
+			}
+			items, ok := value.(bson.A)
+			if elemType.Kind() != reflect.Struct || !ok {
+				continue
+			}
+			for j, item := range items {
+				if nested, ok := asDocument(item); ok && renameLegacyFields(nested, elemType) {
+					items[j] = nested
+					changed = true
+				}
+			}
+		}
+	}
+	return changed
+}
+
+// asDocument returns an embedded document as bson.M whichever form it was decoded in
+func asDocument(value interface{}) (bson.M, bool) {
+	switch doc := value.(type) {
+	case bson.M:
+		return doc, true
+	case primitive.D:
+		return doc.Map(), true
+	}
+	return nil, false
+}
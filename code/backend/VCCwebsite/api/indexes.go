@@ -0,0 +1,134 @@
+package api
+
+import (
+	"context"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// EnsureIndexes creates the MongoDB indexes the API relies on. It is safe to call on
+// every startup; existing indexes with the same definition are left untouched.
+func EnsureIndexes(ctx context.Context, client *mongo.Client) error {
+	if client == nil {
+		return nil
+	}
+	database := client.Database("vccwebsite")
+	versions := database.Collection("scripts_versions")
+
+	if err := ensureScriptTextIndex(ctx, database.Collection("scripts")); err != nil {
+		return err
+	}
+
+	// The trash listing and the purger only look at trashed records
+	for _, name := range []string{"scripts", "script_requests"} {
+		_, err := database.Collection(name).Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys:    bson.D{{Key: deletedAtField, Value: -1}},
+			Options: options.Index().SetSparse(true).SetName("deleted_at"),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// One stored artifact per content digest. Artifacts catalogued without a digest
+	// are left out; the index used to cover every entry.
+	blobs := database.Collection(artifactBlobsCollection)
+	digestIndex := mongo.IndexModel{
+		Keys: bson.D{{Key: "sha256", Value: 1}},
+		Options: options.Index().SetUnique(true).SetName("sha256_unique").
+			SetPartialFilterExpression(bson.M{"sha256": bson.M{"$exists": true}}),
+	}
+	_, err := blobs.Indexes().CreateOne(ctx, digestIndex)
+	if isIndexConflict(err) {
+		if _, err = blobs.Indexes().DropOne(ctx, "sha256_unique"); err == nil {
+			_, err = blobs.Indexes().CreateOne(ctx, digestIndex)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	// GET /api/artifacts lists the newest uploads first
+	_, err = blobs.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "uploaded_at", Value: -1}},
+		Options: options.Index().SetName("uploaded_at"),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Histories written before the index existed may contain duplicate numbers
+	if err := renumberDuplicateVersions(ctx, versions); err != nil {
+		return err
+	}
+
+	// One entry per (document, version) so concurrent saves cannot share a number
+	_, err = versions.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "document_id", Value: 1},
+			{Key: "version_number", Value: 1},
+		},
+		Options: options.Index().SetUnique(true).SetName("document_version_unique"),
+	})
+	return err
+}
+
+// renumberDuplicateVersions rewrites the history of every document that has duplicate
+// version numbers so it runs 1..n in save order, and resets its version counter.
+func renumberDuplicateVersions(ctx context.Context, versions *mongo.Collection) error {
+	cursor, err := versions.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$group", Value: bson.M{
+			"_id":   bson.M{"document_id": "$document_id", "version_number": "$version_number"},
+			"count": bson.M{"$sum": 1},
+		}}},
+		{{Key: "$match", Value: bson.M{"count": bson.M{"$gt": 1}}}},
+		{{Key: "$group", Value: bson.M{"_id": "$_id.document_id"}}},
+	})
+	if err != nil {
+		return err
+	}
+	var affected []struct {
+		DocumentID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &affected); err != nil {
+		return err
+	}
+
+	counters := versions.Database().Collection(versionCountersCollection)
+	for _, doc := range affected {
+		opts := options.Find().
+			SetSort(bson.D{{Key: "version_number", Value: 1}, {Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}).
+			SetProjection(bson.M{"_id": 1})
+		cursor, err := versions.Find(ctx, bson.M{"document_id": doc.DocumentID}, opts)
+		if err != nil {
+			return err
+		}
+		var entries []struct {
+			ID primitive.ObjectID `bson:"_id"`
+		}
+		if err := cursor.All(ctx, &entries); err != nil {
+			return err
+		}
+
+		for i, entry := range entries {
+			_, err := versions.UpdateOne(ctx, bson.M{"_id": entry.ID}, bson.M{"$set": bson.M{"version_number": i + 1}})
+			if err != nil {
+				return err
+			}
+		}
+		_, err = counters.UpdateOne(ctx,
+			bson.M{"_id": doc.DocumentID},
+			bson.M{"$set": bson.M{"seq": len(entries)}},
+			options.Update().SetUpsert(true),
+		)
+		if err != nil {
+			return err
+		}
+		log.Printf("renumbered %d versions of document %s", len(entries), doc.DocumentID.Hex())
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"VCCwebsite/internal/artifactstore"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Problems reported for a broken link
+const (
+	linkMissing   = "missing"    // the target does not exist
+	linkTrashed   = "trashed"    // a live record points at a record in the trash it does not go with
+	linkInvalidID = "invalid_id" // the stored ID is not an ObjectID
+)
+
+// brokenLink is one reference whose target cannot be resolved
+type brokenLink struct {
+	Kind       string `json:"kind"`
+	Collection string `json:"collection"`
+	ID         string `json:"id"`
+	TargetID   string `json:"target_id"`
+	Problem    string `json:"problem"`
+}
+
+// integrityReport is the response of GET /api/integrity
+type integrityReport struct {
+	CheckedAt time.Time      `json:"checked_at"`
+	Broken    []brokenLink   `json:"broken"`
+	Counts    map[string]int `json:"counts"` // broken links per reference kind
+}
+
+// IntegrityHandler checks every reference in referenceKinds and reports the broken ones
+// GET /api/integrity
+// GET /api/integrity?kind=script_artifact,request_approved_script - only these kinds
+func IntegrityHandler(client *mongo.Client, store artifactstore.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if client == nil {
+			respondWithError(w, http.StatusServiceUnavailable, "Database connection not available")
+			return
+		}
+		if r.Method != http.MethodGet {
+			respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		kinds := referenceKinds
+		if raw := r.URL.Query().Get("kind"); raw != "" {
+			kinds = nil
+			for _, name := range strings.Split(raw, ",") {
+				kind, ok := referenceKindNamed(strings.TrimSpace(name))
+				if !ok {
+					respondWithError(w, http.StatusBadRequest, "Unknown reference kind: "+name)
+					return
+				}
+				kinds = append(kinds, kind)
+			}
+		}
+
+		// A full scan of every collection; give it longer than a single-record request
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		report, err := checkIntegrity(ctx, client.Database("vccwebsite"), store, kinds)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error checking references")
+			return
+		}
+		respondWithJSON(w, http.StatusOK, report)
+	}
+}
+
+func referenceKindNamed(name string) (referenceKind, bool) {
+	for _, kind := range referenceKinds {
+		if kind.Name == name {
+			return kind, true
+		}
+	}
+	return referenceKind{}, false
+}
+
+// checkIntegrity resolves every reference of the given kinds against its target
+// collection, or the artifact store. Artifact links are skipped without a store.
+func checkIntegrity(ctx context.Context, database *mongo.Database, store artifactstore.Store, kinds []referenceKind) (integrityReport, error) {
+	report := integrityReport{
+		CheckedAt: time.Now().UTC(),
+		Broken:    []brokenLink{},
+		Counts:    map[string]int{},
+	}
+
+	for _, kind := range kinds {
+		if kind.Target == "artifact" && store == nil {
+			continue
+		}
+
+		type link struct {
+			id       string
+			target   string
+			trashed  bool // the referencing record is itself in the trash
+			targetID primitive.ObjectID
+		}
+		var links []link
+		targetIDs := map[primitive.ObjectID]bool{}
+
+		opts := options.Find().SetProjection(bson.M{kind.Field: 1, deletedAtField: 1})
+		cursor, err := database.Collection(kind.Collection).Find(ctx, bson.M{kind.Field: bson.M{"$exists": true}}, opts)
+		if err != nil {
+			return report, err
+		}
+		var records []bson.M
+		if err := cursor.All(ctx, &records); err != nil {
+			return report, err
+		}
+
+		for _, record := range records {
+			id := ""
+			if oid, ok := record["_id"].(primitive.ObjectID); ok {
+				id = oid.Hex()
+			}
+			_, trashed := record[deletedAtField]
+			for _, value := range valuesAt(record, strings.Split(kind.Field, ".")) {
+				l := link{id: id, trashed: trashed}
+				switch v := value.(type) {
+				case primitive.ObjectID:
+					l.target, l.targetID = v.Hex(), v
+				case string:
+					if v == "" {
+						continue
+					}
+					l.target = v
+					oid, err := primitive.ObjectIDFromHex(v)
+					if err != nil {
+						report.add(kind, l.id, v, linkInvalidID)
+						continue
+					}
+					l.targetID = oid
+				default:
+					continue
+				}
+				links = append(links, l)
+				targetIDs[l.targetID] = true
+			}
+		}
+		if len(links) == 0 {
+			continue
+		}
+
+		var found map[primitive.ObjectID]bool
+		if kind.Target == "artifact" {
+			found, err = resolveArtifacts(ctx, store, targetIDs)
+		} else {
+			found, err = resolveTargets(ctx, database.Collection(referenceTargets[kind.Target]), targetIDs)
+		}
+		if err != nil {
+			return report, err
+		}
+		for _, l := range links {
+			targetTrashed, ok := found[l.targetID]
+			switch {
+			case !ok:
+				report.add(kind, l.id, l.target, linkMissing)
+			case targetTrashed && !l.trashed && kind.OnDelete != onDeleteCascade:
+				report.add(kind, l.id, l.target, linkTrashed)
+			}
+		}
+	}
+	return report, nil
+}
+
+// resolveTargets looks the IDs up and reports, for each one that exists, whether it
+// is in the trash
+func resolveTargets(ctx context.Context, collection *mongo.Collection, ids map[primitive.ObjectID]bool) (map[primitive.ObjectID]bool, error) {
+	list := make(bson.A, 0, len(ids))
+	for id := range ids {
+		list = append(list, id)
+	}
+
+	opts := options.Find().SetProjection(bson.M{"_id": 1, deletedAtField: 1})
+	cursor, err := collection.Find(ctx, bson.M{"_id": bson.M{"$in": list}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var records []bson.M
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+
+	found := make(map[primitive.ObjectID]bool, len(records))
+	for _, record := range records {
+		if id, ok := record["_id"].(primitive.ObjectID); ok {
+			_, trashed := record[deletedAtField]
+			found[id] = trashed
+		}
+	}
+	return found, nil
+}
+
+// resolveArtifacts is resolveTargets for the artifact store, where nothing is trashed
+func resolveArtifacts(ctx context.Context, store artifactstore.Store, ids map[primitive.ObjectID]bool) (map[primitive.ObjectID]bool, error) {
+	found := make(map[primitive.ObjectID]bool, len(ids))
+	for id := range ids {
+		_, err := store.Stat(ctx, id.Hex())
+		if err == artifactstore.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		found[id] = false
+	}
+	return found, nil
+}
+
+func (report *integrityReport) add(kind referenceKind, id, target, problem string) {
+	report.Broken = append(report.Broken, brokenLink{
+		Kind:       kind.Name,
+		Collection: kind.Collection,
+		ID:         id,
+		TargetID:   target,
+		Problem:    problem,
+	})
+	report.Counts[kind.Name]++
+}
@@ -0,0 +1,163 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Page sizes shared by the list endpoints
+const (
+	defaultListLimit = 50 // used when only page= is given
+	maxListLimit     = 500
+)
+
+// sortKey is one field of a sort= parameter
+type sortKey struct {
+	Field string // storage field (Mongo path or SQL column)
+	Desc  bool
+}
+
+// listParams are the pagination and sort options of a list request.
+// A zero Limit means the caller asked for every row.
+type listParams struct {
+	Page  int
+	Limit int
+	Sort  []sortKey
+}
+
+// Offset is the number of rows skipped before the requested page
+func (p listParams) Offset() int {
+	if p.Limit == 0 {
+		return 0
+	}
+	return (p.Page - 1) * p.Limit
+}
+
+// parseListParams reads page, limit and sort from the query string.
+//
+//	?page=2&limit=25          - 1-based page of at most limit rows
+//	?sort=-updated_at,author  - comma separated fields, "-" for descending
+//
+// sortable maps the public sort names to storage fields; defaultSort applies when no
+// sort is given.
+func parseListParams(r *http.Request, sortable map[string]string, defaultSort ...sortKey) (listParams, error) {
+	query := r.URL.Query()
+	params := listParams{Page: 1}
+
+	if raw := query.Get("limit"); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit < 1 {
+			return params, fmt.Errorf("limit must be a positive integer")
+		}
+		if limit > maxListLimit {
+			limit = maxListLimit
+		}
+		params.Limit = limit
+	}
+	if raw := query.Get("page"); raw != "" {
+		page, err := strconv.Atoi(raw)
+		if err != nil || page < 1 {
+			return params, fmt.Errorf("page must be a positive integer")
+		}
+		params.Page = page
+		if params.Limit == 0 {
+			params.Limit = defaultListLimit
+		}
+	}
+
+	for _, name := range strings.Split(query.Get("sort"), ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		desc := strings.HasPrefix(name, "-")
+		name = strings.TrimPrefix(strings.TrimPrefix(name, "-"), "+")
+		field, ok := sortable[name]
+		if !ok {
+			return params, fmt.Errorf("cannot sort by %q; sortable fields are %s", name, sortableNames(sortable))
+		}
+		params.Sort = append(params.Sort, sortKey{Field: field, Desc: desc})
+	}
+	if len(params.Sort) == 0 {
+		params.Sort = defaultSort
+	}
+	return params, nil
+}
+
+func sortableNames(sortable map[string]string) string {
+	names := make([]string, 0, len(sortable))
+	for name := range sortable {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// uncollatedSortFields are storage fields holding numbers or RFC 3339 timestamps, which
+// sort the same without a collation
+var uncollatedSortFields = map[string]bool{
+	"created_at":  true,
+	"updated_at":  true,
+	"uploaded_at": true,
+	"size":        true,
+}
+
+// findOptions turns the params into Mongo find options. Strings sort case-insensitively
+// like the actor list, and _id is always the last sort key so pages are stable when the
+// sort fields tie. The collation is only set when a string field is sorted on, since a
+// collated query cannot use the plain indexes.
+func (p listParams) findOptions() *options.FindOptions {
+	order := bson.D{}
+	collate := false
+	for _, key := range p.Sort {
+		direction := 1
+		if key.Desc {
+			direction = -1
+		}
+		order = append(order, bson.E{Key: key.Field, Value: direction})
+		if !uncollatedSortFields[key.Field] {
+			collate = true
+		}
+	}
+	order = append(order, bson.E{Key: "_id", Value: 1})
+
+	opts := options.Find().SetSort(order)
+	if collate {
+		opts.SetCollation(&options.Collation{Locale: "en", Strength: 2})
+	}
+	if p.Limit > 0 {
+		opts.SetSkip(int64(p.Offset())).SetLimit(int64(p.Limit))
+	}
+	return opts
+}
+
+// orderBy turns the params into an SQL ORDER BY clause. Fields come from the sortable
+// map, never from the request, so they are safe to interpolate.
+func (p listParams) orderBy(tiebreak string) string {
+	clauses := make([]string, 0, len(p.Sort)+1)
+	for _, key := range p.Sort {
+		clause := key.Field + " COLLATE NOCASE"
+		if key.Desc {
+			clause += " DESC"
+		}
+		clauses = append(clauses, clause)
+	}
+	clauses = append(clauses, tiebreak)
+	return " ORDER BY " + strings.Join(clauses, ", ")
+}
+
+// setListHeaders reports the total number of matching rows and, for paged requests,
+// the page that was returned
+func setListHeaders(w http.ResponseWriter, p listParams, total int64) {
+	w.Header().Set("X-Total-Count", strconv.FormatInt(total, 10))
+	if p.Limit > 0 {
+		w.Header().Set("X-Page", strconv.Itoa(p.Page))
+		w.Header().Set("X-Per-Page", strconv.Itoa(p.Limit))
+	}
+}
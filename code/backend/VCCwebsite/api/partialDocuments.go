@@ -6,73 +6,82 @@ import (
 	"net/http"
 	"time"
 
-	"VCCwebsite/internal/model"
+	"VCCwebsite/internal/validation"
 
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
 // HandleGetMedications retrieves just the medications for a document
 // GET /api/document/medications?id=xxx
+// Equivalent to GET /api/document?id=xxx&fields=med_hist.medications, unwrapped.
 func HandleGetMedications(w http.ResponseWriter, r *http.Request, collection *mongo.Collection) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	docID := r.URL.Query().Get("id")
-	if docID == "" {
-		respondWithError(w, http.StatusBadRequest, "Document ID is required")
-		return
-	}
-
-	objectID, err := primitive.ObjectIDFromHex(docID)
-	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid document ID format")
-		return
-	}
-
-	var doc scripts.StandardizedScript
-	err = collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&doc)
-	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			respondWithError(w, http.StatusNotFound, "Document not found")
-			return
-		}
-		respondWithError(w, http.StatusInternalServerError, "Error retrieving document")
+	doc, ok := findProjectedDocument(ctx, w, r, collection, "med_hist.medications")
+	if !ok {
 		return
 	}
 
 	respondWithJSON(w, http.StatusOK, doc.MedHist.Medications)
 }
 
-// HandleGetVitals retrieves just the vitals for a document
+// HandleGetVitals retrieves just the vitals for a document, with BMI, orthostatic
+// changes and display strings derived server-side
 // GET /api/document/vitals?id=xxx
+// GET /api/document/vitals?id=xxx&phase=deterioration - vitals of a named timeline phase
+// GET /api/document/vitals?id=xxx&phase=12 - vitals in effect at minute 12
+// GET /api/document/vitals?id=xxx&phase=all - baseline and every timeline phase
+// GET /api/document/vitals?id=xxx&check=true - include plausibility errors and warnings
 func HandleGetVitals(w http.ResponseWriter, r *http.Request, collection *mongo.Collection) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	docID := r.URL.Query().Get("id")
-	if docID == "" {
-		respondWithError(w, http.StatusBadRequest, "Document ID is required")
+	doc, ok := findProjectedDocument(ctx, w, r, collection,
+		"patient.age", "patient.vitals", "patient.vitals_timeline", "sp.current_ill_history.pain")
+	if !ok {
 		return
 	}
 
-	objectID, err := primitive.ObjectIDFromHex(docID)
-	if err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid document ID format")
-		return
-	}
+	query := r.URL.Query()
+	check := query.Get("check") == "true"
+	phases := vitalsPhases(doc)
 
-	var doc scripts.StandardizedScript
-	err = collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&doc)
-	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			respondWithError(w, http.StatusNotFound, "Document not found")
+	if query.Get("phase") == "all" {
+		views := make([]vitalsResponse, 0, len(phases))
+		for _, phase := range phases {
+			views = append(views, vitalsView(doc, phase))
+		}
+		if check {
+			report := validation.ScriptVitals(&doc)
+			respondWithJSON(w, http.StatusOK, map[string]interface{}{
+				"timeline": views,
+				"age":      doc.Patient.Age,
+				"errors":   report.Errors,
+				"warnings": report.Warnings,
+			})
 			return
 		}
-		respondWithError(w, http.StatusInternalServerError, "Error retrieving document")
+		respondWithJSON(w, http.StatusOK, views)
+		return
+	}
+
+	phase, ok := findVitalsPhase(phases, query.Get("phase"))
+	if !ok {
+		respondWithError(w, http.StatusNotFound, "Vitals phase not found")
+		return
+	}
+
+	if check {
+		report := validation.VitalsAt(phase.path, phase.Vitals, doc.Patient.Age)
+		respondWithJSON(w, http.StatusOK, map[string]interface{}{
+			"vitals":   vitalsView(doc, phase),
+			"age":      doc.Patient.Age,
+			"errors":   report.Errors,
+			"warnings": report.Warnings,
+		})
 		return
 	}
 
-	respondWithJSON(w, http.StatusOK, doc.Patient.Vitals)
+	respondWithJSON(w, http.StatusOK, vitalsView(doc, phase))
 }
@@ -0,0 +1,163 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"VCCwebsite/internal/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// summaryFields is the view=summary projection: what a script list row shows
+var summaryFields = []string{
+	"admin.reson_for_visit",
+	"admin.chief_concern",
+	"admin.diagnosis",
+	"admin.class",
+	"admin.medical_event",
+	"admin.learner_level",
+	"admin.academic_year",
+	"admin.author",
+	"patient.name",
+	"source_request_id",
+	"created_at",
+	"updated_at",
+}
+
+// documentProjection reads fields= and view= from the query string and returns the
+// Mongo projection to apply, or nil when the whole script was asked for.
+//
+//	?fields=admin.diagnosis,patient.vitals - only these paths (JSON names)
+//	?view=summary                          - the summaryFields
+//	?view=full                             - everything (the default)
+func documentProjection(r *http.Request) (bson.M, error) {
+	query := r.URL.Query()
+	var paths []string
+
+	switch view := query.Get("view"); view {
+	case "", "full":
+	case "summary":
+		paths = append(paths, summaryFields...)
+	default:
+		return nil, fmt.Errorf("unknown view %q; use summary or full", view)
+	}
+
+	for _, path := range strings.Split(query.Get("fields"), ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		if path == "id" {
+			continue // always returned
+		}
+		if !scriptPathExists(path) {
+			return nil, fmt.Errorf("unknown field %q", path)
+		}
+		paths = append(paths, path)
+	}
+
+	if len(paths) == 0 {
+		return nil, nil
+	}
+	return projectionFor(paths), nil
+}
+
+// projectionFor builds an inclusion projection. Paths nested under another requested
+// path are dropped since Mongo rejects overlapping projections.
+func projectionFor(paths []string) bson.M {
+	projection := bson.M{}
+	for _, path := range paths {
+		covered := false
+		for _, other := range paths {
+			if other != path && strings.HasPrefix(path, other+".") {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			projection[path] = 1
+		}
+	}
+	return projection
+}
+
+// scriptPathExists reports whether a dotted JSON path names a field of
+// StandardizedScript. Array elements are addressed without an index.
+func scriptPathExists(path string) bool {
+	t := reflect.TypeOf(scripts.StandardizedScript{})
+	for _, name := range strings.Split(path, ".") {
+		for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct {
+			return false
+		}
+		field, ok := fieldByJSONName(t, name)
+		if !ok {
+			return false
+		}
+		t = field.Type
+	}
+	return true
+}
+
+func fieldByJSONName(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if strings.Split(field.Tag.Get("json"), ",")[0] == name {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// projectedDocument is a script reduced to the requested paths, with its ID as "id"
+func projectedDocument(rawDoc bson.M) bson.M {
+	doc := bson.M{}
+	for key, value := range rawDoc {
+		if key == "_id" {
+			if id, ok := value.(primitive.ObjectID); ok {
+				doc["id"] = id.Hex()
+			}
+			continue
+		}
+		doc[key] = value
+	}
+	return doc
+}
+
+// findProjectedDocument loads the script named by the id query parameter with only
+// the given paths, writing the error response itself when it returns false
+func findProjectedDocument(ctx context.Context, w http.ResponseWriter, r *http.Request, collection *mongo.Collection, paths ...string) (scripts.StandardizedScript, bool) {
+	var doc scripts.StandardizedScript
+
+	docID := r.URL.Query().Get("id")
+	if docID == "" {
+		respondWithError(w, http.StatusBadRequest, "Document ID is required")
+		return doc, false
+	}
+	objectID, err := primitive.ObjectIDFromHex(docID)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid document ID format")
+		return doc, false
+	}
+
+	opts := options.FindOne().SetProjection(projectionFor(paths))
+	err = collection.FindOne(ctx, liveFilter(objectID), opts).Decode(&doc)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			respondWithError(w, http.StatusNotFound, "Document not found")
+			return doc, false
+		}
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving document")
+		return doc, false
+	}
+	return doc, true
+}
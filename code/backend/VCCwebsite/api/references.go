@@ -0,0 +1,244 @@
+package api
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"VCCwebsite/internal/artifactstore"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// onDelete is what happens to a reference when the record it points at is deleted
+type onDelete string
+
+const (
+	onDeleteBlock   onDelete = "block"   // the target cannot be deleted while the reference exists
+	onDeleteCascade onDelete = "cascade" // the referencing record is deleted with the target
+	onDeleteUnlink  onDelete = "unlink"  // the reference field is cleared
+	onDeleteKeep    onDelete = "keep"    // the reference is left as is and reported by the integrity check
+)
+
+// referenceKind is one field that holds the ID of another record
+type referenceKind struct {
+	Name       string
+	Collection string // collection of the referencing records
+	Field      string // dotted path of the ID; arrays are walked
+	Target     string // "artifact", "document" or "request"
+	ObjectID   bool   // the field stores an ObjectID rather than its hex string
+	OnDelete   onDelete
+}
+
+// referenceKinds lists every link between scripts, versions, requests and artifacts.
+// Artifacts attached to a live or trashed script or request cannot be deleted; old
+// versions do not hold them back, since restoring a version is rare and the integrity
+// check reports what it would miss.
+//
+// Moving a script or request to the trash deliberately skips these rules: it can be
+// undone, and a trashed record still satisfies every reference to it. The rules apply
+// when the trash is purged. The DELETE responses list the references found so the
+// caller can see what the purge will release.
+var referenceKinds = []referenceKind{
+	{Name: "script_artifact", Collection: "scripts", Field: "artifacts.id", Target: "artifact", OnDelete: onDeleteBlock},
+	{Name: "request_artifact", Collection: "script_requests", Field: "artifacts.id", Target: "artifact", OnDelete: onDeleteBlock},
+	{Name: "request_draft_artifact", Collection: "script_requests", Field: "draft_script.artifacts.id", Target: "artifact", OnDelete: onDeleteBlock},
+	{Name: "version_artifact", Collection: "scripts_versions", Field: "document.artifacts.id", Target: "artifact", OnDelete: onDeleteKeep},
+	{Name: "version_document", Collection: "scripts_versions", Field: "document_id", Target: "document", ObjectID: true, OnDelete: onDeleteCascade},
+	{Name: "request_approved_script", Collection: "script_requests", Field: "approved_script_id", Target: "document", OnDelete: onDeleteUnlink},
+	{Name: "script_source_request", Collection: "scripts", Field: "source_request_id", Target: "request", OnDelete: onDeleteUnlink},
+}
+
+// referenceTargets maps a reference target to the collection its records live in.
+// Artifacts live in the artifact store instead.
+var referenceTargets = map[string]string{
+	"document": "scripts",
+	"request":  "script_requests",
+}
+
+// filter matches the records holding a reference to id
+func (k referenceKind) filter(id primitive.ObjectID) bson.M {
+	if k.ObjectID {
+		return bson.M{k.Field: id}
+	}
+	return bson.M{k.Field: id.Hex()}
+}
+
+// reference is one record pointing at the record being deleted
+type reference struct {
+	Kind       string   `json:"kind"`
+	Collection string   `json:"collection"`
+	ID         string   `json:"id"`
+	Trashed    bool     `json:"trashed,omitempty"`
+	OnDelete   onDelete `json:"on_delete"`
+}
+
+// findReferences lists the records that point at the target record with the given ID
+func findReferences(ctx context.Context, database *mongo.Database, target string, id primitive.ObjectID) ([]reference, error) {
+	refs := []reference{}
+	for _, kind := range referenceKinds {
+		if kind.Target != target {
+			continue
+		}
+		opts := options.Find().SetProjection(bson.M{"_id": 1, deletedAtField: 1})
+		cursor, err := database.Collection(kind.Collection).Find(ctx, kind.filter(id), opts)
+		if err != nil {
+			return nil, err
+		}
+		var records []struct {
+			ID        primitive.ObjectID `bson:"_id"`
+			DeletedAt interface{}        `bson:"deleted_at"`
+		}
+		if err := cursor.All(ctx, &records); err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			refs = append(refs, reference{
+				Kind:       kind.Name,
+				Collection: kind.Collection,
+				ID:         record.ID.Hex(),
+				Trashed:    record.DeletedAt != nil,
+				OnDelete:   kind.OnDelete,
+			})
+		}
+	}
+	return refs, nil
+}
+
+// blockingReferences returns the references that forbid deleting their target
+func blockingReferences(refs []reference) []reference {
+	blocking := []reference{}
+	for _, ref := range refs {
+		if ref.OnDelete == onDeleteBlock {
+			blocking = append(blocking, ref)
+		}
+	}
+	return blocking
+}
+
+// releaseReferences applies the cascade and unlink rules once the target record with
+// the given ID is gone. Blocking references must have been checked by the caller.
+func releaseReferences(ctx context.Context, database *mongo.Database, target string, id primitive.ObjectID) error {
+	for _, kind := range referenceKinds {
+		if kind.Target != target {
+			continue
+		}
+		collection := database.Collection(kind.Collection)
+		switch kind.OnDelete {
+		case onDeleteCascade:
+			if _, err := collection.DeleteMany(ctx, kind.filter(id)); err != nil {
+				return err
+			}
+		case onDeleteUnlink:
+			if _, err := collection.UpdateMany(ctx, kind.filter(id), bson.M{"$unset": bson.M{kind.Field: ""}}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ownedArtifacts lists the artifact IDs referenced by a script and its versions, or by
+// a request, so they can be checked for orphans once the record is purged
+func ownedArtifacts(ctx context.Context, database *mongo.Database, target string, id primitive.ObjectID) ([]string, error) {
+	owners := map[string]bson.M{}
+	switch target {
+	case "document":
+		owners["scripts"] = bson.M{"_id": id}
+		owners["scripts_versions"] = bson.M{"document_id": id}
+	case "request":
+		owners["script_requests"] = bson.M{"_id": id}
+	}
+
+	seen := map[string]bool{}
+	ids := []string{}
+	for _, kind := range referenceKinds {
+		filter, ok := owners[kind.Collection]
+		if kind.Target != "artifact" || !ok {
+			continue
+		}
+		cursor, err := database.Collection(kind.Collection).Find(ctx, filter, options.Find().SetProjection(bson.M{kind.Field: 1}))
+		if err != nil {
+			return nil, err
+		}
+		var records []bson.M
+		if err := cursor.All(ctx, &records); err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			for _, value := range valuesAt(record, strings.Split(kind.Field, ".")) {
+				if artifactID, ok := value.(string); ok && artifactID != "" && !seen[artifactID] {
+					seen[artifactID] = true
+					ids = append(ids, artifactID)
+				}
+			}
+		}
+	}
+	return ids, nil
+}
+
+// deleteOrphanedArtifacts removes the given artifacts from the store when nothing refers
+// to them any more and returns how many were deleted
+func deleteOrphanedArtifacts(ctx context.Context, database *mongo.Database, store artifactstore.Store, artifactIDs []string) (int, error) {
+	if store == nil {
+		return 0, nil
+	}
+
+	// Uploads deduplicated onto an artifact may not be attached anywhere yet
+	cutoff := time.Now().UTC().Add(-defaultArtifactGrace)
+	claimed, err := recentlyClaimedArtifacts(ctx, database, cutoff)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for _, artifactID := range artifactIDs {
+		if claimed[artifactID] {
+			continue // left for the sweeper
+		}
+		objectID, err := primitive.ObjectIDFromHex(artifactID)
+		if err != nil {
+			continue
+		}
+		refs, err := findReferences(ctx, database, "artifact", objectID)
+		if err != nil {
+			return deleted, err
+		}
+		if len(refs) > 0 {
+			continue
+		}
+		err = removeArtifact(ctx, database, store, artifactID, claimedBefore(cutoff))
+		if err == errArtifactClaimed {
+			continue
+		}
+		if err != nil && err != artifactstore.ErrNotFound {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// valuesAt collects the values at a dotted path, walking into every array on the way
+func valuesAt(value interface{}, path []string) []interface{} {
+	if arr, ok := value.(bson.A); ok {
+		var values []interface{}
+		for _, elem := range arr {
+			values = append(values, valuesAt(elem, path)...)
+		}
+		return values
+	}
+	if len(path) == 0 {
+		if value == nil {
+			return nil
+		}
+		return []interface{}{value}
+	}
+	if doc, ok := value.(bson.M); ok {
+		return valuesAt(doc[path[0]], path[1:])
+	}
+	return nil
+}
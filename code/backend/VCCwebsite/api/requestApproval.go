@@ -0,0 +1,254 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"VCCwebsite/internal/model"
+	"VCCwebsite/internal/validation"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// statusError carries the HTTP status a request failed with
+type statusError struct {
+	status  int
+	message string
+}
+
+func (e *statusError) Error() string { return e.message }
+
+// respondWithStatusError maps statusErrors to their status, validation failures to 422
+// and everything else to 500
+func respondWithStatusError(w http.ResponseWriter, err error, fallback string) {
+	var statusErr *statusError
+	if errors.As(err, &statusErr) {
+		respondWithError(w, statusErr.status, statusErr.message)
+		return
+	}
+	var fieldErrs validation.Errors
+	if errors.As(err, &fieldErrs) {
+		respondWithValidationError(w, err)
+		return
+	}
+	respondWithError(w, http.StatusInternalServerError, fallback)
+}
+
+// approvalResult is the response of the approve endpoint
+type approvalResult struct {
+	Request ScriptRequestWithID `json:"request"`
+	Script  DocumentWithID      `json:"script"`
+}
+
+// handleApproveScriptRequest approves a request and converts it into a script
+// POST /api/script-request/approve?id=xxx   {"note": "..."} (optional)
+//
+// The script is created from the request's draft_script when present, otherwise from
+// the request fields. Artifacts are carried over, the script and request are linked
+// and version 1 of the script is written. All writes happen in one transaction when
+// the server supports it (replica set or sharded cluster); on a standalone server the
+// already written records are removed again if a later step fails. A script that
+// POST /api/document would reject is refused the same way, with 422 and its field
+// errors.
+func handleApproveScriptRequest(w http.ResponseWriter, r *http.Request, client *mongo.Client) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	transition := requestTransitions["approve"]
+	if !callerHasAnyRole(r, transition.Roles...) {
+		respondWithError(w, http.StatusForbidden, "forbidden: requires one of roles "+joinRoleNames(transition.Roles))
+		return
+	}
+
+	objectID, ok := parseScriptRequestID(w, r)
+	if !ok {
+		return
+	}
+
+	var body transitionRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && err != io.EOF {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	note := strings.TrimSpace(body.Note)
+	by := callerIdentity(r)
+
+	var result approvalResult
+	var err error
+	if supportsTransactions(ctx, client) {
+		var session mongo.Session
+		session, err = client.StartSession()
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error starting database session")
+			return
+		}
+		defer session.EndSession(ctx)
+
+		var out interface{}
+		out, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+			return approveScriptRequest(sc, client, objectID, note, by, false)
+		})
+		if err == nil {
+			result = out.(approvalResult)
+		}
+	} else {
+		result, err = approveScriptRequest(ctx, client, objectID, note, by, true)
+	}
+
+	if err != nil {
+		respondWithStatusError(w, err, "Error approving script request")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, result)
+}
+
+// approveScriptRequest performs the approval writes. When compensate is set, records
+// written before a failing step are deleted again.
+func approveScriptRequest(ctx context.Context, client *mongo.Client, requestID primitive.ObjectID, note, by string, compensate bool) (approvalResult, error) {
+	database := client.Database("vccwebsite")
+	requests := database.Collection("script_requests")
+	documents := database.Collection("scripts")
+	versions := database.Collection("scripts_versions")
+
+	var req scripts.ScriptRequest
+	if err := requests.FindOne(ctx, liveFilter(requestID)).Decode(&req); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return approvalResult{}, &statusError{http.StatusNotFound, "Script request not found"}
+		}
+		return approvalResult{}, err
+	}
+
+	from := normalizeRequestStatus(req.Status)
+	if !requestTransitions["approve"].allowsFrom(from) {
+		return approvalResult{}, &statusError{http.StatusConflict, "Cannot move a request from " + from + " to " + scripts.StatusApproved}
+	}
+	if req.ApprovedScriptID != "" {
+		return approvalResult{}, &statusError{http.StatusConflict, "Script request is already linked to script " + req.ApprovedScriptID}
+	}
+
+	script := scriptFromRequest(req)
+	script.SourceRequestID = requestID.Hex()
+	script.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	script.UpdatedAt = script.CreatedAt
+
+	// The request must make a script POST /api/document would accept
+	if err := validation.Script(&script); err != nil {
+		return approvalResult{}, err
+	}
+
+	inserted, err := documents.InsertOne(ctx, script)
+	if err != nil {
+		return approvalResult{}, err
+	}
+	scriptID := inserted.InsertedID.(primitive.ObjectID)
+
+	rollback := func() {
+		if !compensate {
+			return
+		}
+		cctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_, _ = versions.DeleteMany(cctx, bson.M{"document_id": scriptID})
+		_, _ = documents.DeleteOne(cctx, bson.M{"_id": scriptID})
+	}
+
+	_, err = versions.InsertOne(ctx, DocumentVersion{
+		DocumentID:    scriptID,
+		VersionNumber: 1,
+		Document:      script,
+		CreatedAt:     time.Now(),
+		CreatedBy:     by,
+		ChangeNote:    "Created from script request " + requestID.Hex(),
+	})
+	if err != nil {
+		rollback()
+		return approvalResult{}, err
+	}
+
+	updated, err := applyRequestTransition(ctx, requests, requestID, req.Status, scripts.StatusApproved, note, by,
+		bson.M{"approved_script_id": scriptID.Hex()})
+	if err != nil {
+		rollback()
+		if err == mongo.ErrNoDocuments {
+			return approvalResult{}, &statusError{http.StatusConflict, "Script request status changed concurrently, reload and retry"}
+		}
+		return approvalResult{}, err
+	}
+
+	var rawDoc bson.M
+	if err := documents.FindOne(ctx, bson.M{"_id": scriptID}).Decode(&rawDoc); err != nil {
+		return approvalResult{}, err
+	}
+
+	return approvalResult{
+		Request: updated,
+		Script:  convertToDocumentWithID(rawDoc),
+	}, nil
+}
+
+// scriptFromRequest builds the initial script for an approved request
+func scriptFromRequest(req scripts.ScriptRequest) scripts.StandardizedScript {
+	if req.DraftScript != nil {
+		script := *req.DraftScript
+		script.Artifacts = mergeArtifacts(script.Artifacts, req.Artifacts)
+		return script
+	}
+
+	// Only fields with a direct counterpart are copied; the author fills in the rest
+	var script scripts.StandardizedScript
+	script.Admin = scripts.AdminDetails{
+		ResonForVisit:       req.ReasonForVisit,
+		ChiefConcern:        req.ChiefConcern,
+		Diagnosis:           req.Diagnosis,
+		Class:               req.Class,
+		MedicalEvent:        req.Event,
+		LearnerLevel:        req.LearnerLevel,
+		SummoryOfStory:      req.SummaryPatientStory,
+		StudentExpectations: req.StudentExpec,
+		PatientDemographic:  req.PatientDemog,
+		CaseFactors:         req.CaseFactors,
+	}
+	script.Patient.VisitReason = req.ReasonForVisit
+	script.Patient.Context = req.CaseSetting
+	script.SP.PhysicalChars = req.PhysicalChars
+	script.MedHist.SymptonReview = req.SymptReview
+	script.Artifacts = mergeArtifacts(nil, req.Artifacts)
+	return script
+}
+
+// mergeArtifacts appends extra to base, skipping artifacts already present by ID
+func mergeArtifacts(base, extra []scripts.Artifact) []scripts.Artifact {
+	seen := make(map[string]bool, len(base))
+	merged := make([]scripts.Artifact, 0, len(base)+len(extra))
+	for _, list := range [][]scripts.Artifact{base, extra} {
+		for _, artifact := range list {
+			if artifact.ID != "" && seen[artifact.ID] {
+				continue
+			}
+			seen[artifact.ID] = true
+			merged = append(merged, artifact)
+		}
+	}
+	return merged
+}
+
+// supportsTransactions reports whether the deployment is a replica set or mongos
+func supportsTransactions(ctx context.Context, client *mongo.Client) bool {
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	err := client.Database("admin").RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello)
+	if err != nil {
+		return false
+	}
+	return hello.SetName != "" || hello.Msg == "isdbgrid"
+}
@@ -5,9 +5,11 @@ import (
 	"context"
 	"encoding/json"
 	"net/http"
+	"strings"
 	"time"
 
 	"VCCwebsite/internal/model"
+	"VCCwebsite/internal/validation"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -32,6 +34,46 @@ func ScriptRequestHandler(client *mongo.Client) http.HandlerFunc {
 
 		collection := client.Database("vccwebsite").Collection("script_requests")
 
+		path := r.URL.Path
+
+		// Check for /api/script-request/history (status transition history)
+		if strings.HasSuffix(path, "/history") {
+			if r.Method == http.MethodGet {
+				handleGetScriptRequestHistory(w, r, collection)
+				return
+			}
+			respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		// Check for /api/script-request/undelete (take a request out of the trash)
+		if strings.HasSuffix(path, "/undelete") {
+			if r.Method == http.MethodPost {
+				handleUndeleteScriptRequest(w, r, collection)
+				return
+			}
+			respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		// Check for /api/script-request/{action} (workflow transitions)
+		if action := strings.TrimPrefix(path, "/api/script-request/"); action != path && action != "" {
+			if _, ok := requestTransitions[action]; !ok {
+				respondWithError(w, http.StatusNotFound, "Unknown script request action")
+				return
+			}
+			if r.Method == http.MethodPost {
+				if action == "approve" {
+					handleApproveScriptRequest(w, r, client)
+					return
+				}
+				handleScriptRequestTransition(w, r, collection, action)
+				return
+			}
+			respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
 		switch r.Method {
 		case http.MethodGet:
 			handleGetScriptRequests(w, r, collection)
@@ -39,6 +81,8 @@ func ScriptRequestHandler(client *mongo.Client) http.HandlerFunc {
 			handleCreateScriptRequest(w, r, collection)
 		case http.MethodPut:
 			handleUpdateScriptRequest(w, r, collection)
+		case http.MethodPatch:
+			handlePatchScriptRequest(w, r, collection)
 		case http.MethodDelete:
 			handleDeleteScriptRequest(w, r, collection)
 		default:
@@ -53,6 +97,7 @@ func ScriptRequestHandler(client *mongo.Client) http.HandlerFunc {
 // GET /api/script-request?diagnosis=xxx - search by diagnosis
 // GET /api/script-request?chief_concern=xxx - search by chief concern
 // GET /api/script-request?learner_level=xxx - search by learner level
+// GET /api/script-request?page=1&limit=25&sort=-updated_at - paged and sorted, with X-Total-Count
 func handleGetScriptRequests(w http.ResponseWriter, r *http.Request, collection *mongo.Collection) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -67,7 +112,7 @@ func handleGetScriptRequests(w http.ResponseWriter, r *http.Request, collection
 		}
 
 		var rawDoc bson.M
-		err = collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&rawDoc)
+		err = collection.FindOne(ctx, liveFilter(objectID)).Decode(&rawDoc)
 		if err != nil {
 			if err == mongo.ErrNoDocuments {
 				respondWithError(w, http.StatusNotFound, "Script request not found")
@@ -83,11 +128,23 @@ func handleGetScriptRequests(w http.ResponseWriter, r *http.Request, collection
 		return
 	}
 
+	params, err := parseListParams(r, scriptRequestSortFields)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	// Build filter based on query parameters
 	filter := buildScriptRequestFilterFromQuery(r)
 
+	total, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error counting script requests")
+		return
+	}
+
 	// Get script requests with filter
-	cursor, err := collection.Find(ctx, filter)
+	cursor, err := collection.Find(ctx, filter, params.findOptions())
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Error retrieving script requests")
 		return
@@ -111,6 +168,7 @@ func handleGetScriptRequests(w http.ResponseWriter, r *http.Request, collection
 		requests = []ScriptRequestWithID{}
 	}
 
+	setListHeaders(w, params, total)
 	respondWithJSON(w, http.StatusOK, requests)
 }
 
@@ -126,6 +184,21 @@ func handleCreateScriptRequest(w http.ResponseWriter, r *http.Request, collectio
 		return
 	}
 
+	if err := validation.ScriptRequest(&req); err != nil {
+		respondWithValidationError(w, err)
+		return
+	}
+
+	// New requests always enter the workflow as Pending
+	now := time.Now().UTC().Format(time.RFC3339)
+	req.Status = scripts.StatusPending
+	req.ApprovedScriptID = ""
+	req.History = []scripts.StatusTransition{{To: scripts.StatusPending, By: callerIdentity(r), At: now}}
+	if req.CreatedAt == "" {
+		req.CreatedAt = now
+	}
+	req.UpdatedAt = now
+
 	result, err := collection.InsertOne(ctx, req)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Error creating script request")
@@ -185,6 +258,28 @@ func handleUpdateScriptRequest(w http.ResponseWriter, r *http.Request, collectio
 	// Remove _id from update fields if present
 	delete(updateFields, "_id")
 
+	var current scripts.ScriptRequest
+	err = collection.FindOne(ctx, liveFilter(objectID)).Decode(&current)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			respondWithError(w, http.StatusNotFound, "Script request not found")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving script request")
+		return
+	}
+
+	// Status, history and the approved script link only change through the workflow endpoints
+	delete(updateFields, "history")
+	delete(updateFields, "approved_script_id")
+	if status, ok := updateFields["status"].(string); ok {
+		if status != "" && normalizeRequestStatus(status) != normalizeRequestStatus(current.Status) {
+			respondWithError(w, http.StatusConflict, "Status changes must go through the /api/script-request/{action} endpoints")
+			return
+		}
+		delete(updateFields, "status")
+	}
+
 	// Remove null/empty fields
 	for key, value := range updateFields {
 		if value == nil || value == "" {
@@ -197,11 +292,24 @@ func handleUpdateScriptRequest(w http.ResponseWriter, r *http.Request, collectio
 		return
 	}
 
+	// Validate the request as it will look after the top-level fields are replaced
+	mergedBytes, _ := json.Marshal(updateFields)
+	if err := json.Unmarshal(mergedBytes, &current); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error processing update")
+		return
+	}
+	if err := validation.ScriptRequest(&current); err != nil {
+		respondWithValidationError(w, err)
+		return
+	}
+	delete(updateFields, "created_at")
+	updateFields["updated_at"] = time.Now().UTC().Format(time.RFC3339)
+
 	update := bson.M{
 		"$set": updateFields,
 	}
 
-	result, err := collection.UpdateOne(ctx, bson.M{"_id": objectID}, update)
+	result, err := collection.UpdateOne(ctx, liveFilter(objectID), update)
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Error updating script request")
 		return
@@ -224,7 +332,7 @@ func handleUpdateScriptRequest(w http.ResponseWriter, r *http.Request, collectio
 	respondWithJSON(w, http.StatusOK, updatedReq)
 }
 
-// handleDeleteScriptRequest deletes a script request
+// handleDeleteScriptRequest moves a script request to the trash
 // DELETE /api/script-request?id=xxx
 func handleDeleteScriptRequest(w http.ResponseWriter, r *http.Request, collection *mongo.Collection) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -242,20 +350,28 @@ func handleDeleteScriptRequest(w http.ResponseWriter, r *http.Request, collectio
 		return
 	}
 
-	result, err := collection.DeleteOne(ctx, bson.M{"_id": objectID})
+	// Trashing is reversible, so references only inform; the purge applies them
+	refs, err := findReferences(ctx, collection.Database(), "request", objectID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error checking script request references")
+		return
+	}
+
+	trashed, err := moveToTrash(ctx, collection, objectID, callerIdentity(r))
 	if err != nil {
 		respondWithError(w, http.StatusInternalServerError, "Error deleting script request")
 		return
 	}
 
-	if result.DeletedCount == 0 {
+	if !trashed {
 		respondWithError(w, http.StatusNotFound, "Script request not found")
 		return
 	}
 
-	respondWithJSON(w, http.StatusOK, map[string]string{
-		"message": "Script request deleted successfully",
-		"id":      reqID,
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"message":    "Script request moved to trash",
+		"id":         reqID,
+		"references": refs,
 	})
 }
 
@@ -275,31 +391,45 @@ func convertToScriptRequestWithID(rawDoc bson.M) ScriptRequestWithID {
 	return req
 }
 
+// scriptRequestSortFields are the sort= names accepted by GET /api/script-request. name
+// sorts on the reason for visit, like the document title.
+var scriptRequestSortFields = map[string]string{
+	"name":             "reason_for_visit",
+	"reason_for_visit": "reason_for_visit",
+	"chief_concern":    "chief_concern",
+	"diagnosis":        "diagnosis",
+	"class":            "class",
+	"learner_level":    "learner_level",
+	"status":           "status",
+	"created_at":       "created_at",
+	"updated_at":       "updated_at",
+}
+
 // buildScriptRequestFilterFromQuery builds a MongoDB filter from URL query parameters
 func buildScriptRequestFilterFromQuery(r *http.Request) bson.M {
-	filter := bson.M{}
+	filter := bson.M{deletedAtField: notTrashed}
 	query := r.URL.Query()
 
 	if simModal := query.Get("simulation_modal"); simModal != "" {
-		filter["simulation_modal"] = bson.M{"$regex": simModal, "$options": "i"}
+		filter["simulation_modal"] = containsFilter(simModal)
 	}
 	if caseSetting := query.Get("case_setting"); caseSetting != "" {
-		filter["case_setting"] = bson.M{"$regex": caseSetting, "$options": "i"}
+		filter["case_setting"] = containsFilter(caseSetting)
 	}
 	if chiefConcern := query.Get("chief_concern"); chiefConcern != "" {
-		filter["chief_concern"] = bson.M{"$regex": chiefConcern, "$options": "i"}
+		filter["chief_concern"] = containsFilter(chiefConcern)
 	}
 	if diagnosis := query.Get("diagnosis"); diagnosis != "" {
-		filter["diagnosis"] = bson.M{"$regex": diagnosis, "$options": "i"}
+		filter["diagnosis"] = containsFilter(diagnosis)
 	}
 	if event := query.Get("event"); event != "" {
-		filter["event"] = bson.M{"$regex": event, "$options": "i"}
+		filter["event"] = containsFilter(event)
 	}
 	if pedagogy := query.Get("pedagogy"); pedagogy != "" {
-		filter["pedagogy"] = bson.M{"$regex": pedagogy, "$options": "i"}
+		filter["pedagogy"] = containsFilter(pedagogy)
 	}
 	if class := query.Get("class"); class != "" {
-		filter["class"] = bson.M{"$regex": class, "$options": "i"}
+		filter["class"] = containsFilter(class)
 	}
 	if learnerLevel := query.Get("learner_level"); learnerLevel != "" {
 		filter["learner_level"] = learnerLevel
@@ -308,10 +438,10 @@ func buildScriptRequestFilterFromQuery(r *http.Request) bson.M {
 	// Search query - searches across multiple fields
 	if search := query.Get("search"); search != "" {
 		filter["$or"] = []bson.M{
-			{"chief_concern": bson.M{"$regex": search, "$options": "i"}},
-			{"diagnosis": bson.M{"$regex": search, "$options": "i"}},
-			{"summary_patient_story": bson.M{"$regex": search, "$options": "i"}},
-			{"case_setting": bson.M{"$regex": search, "$options": "i"}},
+			{"chief_concern": containsFilter(search)},
+			{"diagnosis": containsFilter(search)},
+			{"summary_patient_story": containsFilter(search)},
+			{"case_setting": containsFilter(search)},
 		}
 	}
 
@@ -0,0 +1,263 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"VCCwebsite/internal/model"
+	"VCCwebsite/internal/oAuth"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// requestTransition is one edge of the script request workflow
+type requestTransition struct {
+	From         []string
+	To           string
+	Roles        []oAuth.Role
+	NoteRequired bool
+}
+
+var (
+	requestReviewers = []oAuth.Role{oAuth.RoleAdmin, oAuth.RoleReviewer}
+	requestAuthors   = []oAuth.Role{oAuth.RoleAdmin, oAuth.RoleAuthor, oAuth.RoleReviewer}
+)
+
+// requestTransitions maps each workflow endpoint to the transition it performs
+//
+//	Pending → In Review → Needs Changes → In Review → Approved/Rejected → Archived
+var requestTransitions = map[string]requestTransition{
+	"review": {
+		From:  []string{scripts.StatusPending},
+		To:    scripts.StatusInReview,
+		Roles: requestReviewers,
+	},
+	"request-changes": {
+		From:         []string{scripts.StatusInReview},
+		To:           scripts.StatusNeedsChanges,
+		Roles:        requestReviewers,
+		NoteRequired: true,
+	},
+	"resubmit": {
+		From:  []string{scripts.StatusNeedsChanges},
+		To:    scripts.StatusInReview,
+		Roles: requestAuthors,
+	},
+	"approve": {
+		From:  []string{scripts.StatusInReview},
+		To:    scripts.StatusApproved,
+		Roles: requestReviewers,
+	},
+	"reject": {
+		From:         []string{scripts.StatusPending, scripts.StatusInReview, scripts.StatusNeedsChanges},
+		To:           scripts.StatusRejected,
+		Roles:        requestReviewers,
+		NoteRequired: true,
+	},
+	"archive": {
+		From:  []string{scripts.StatusApproved, scripts.StatusRejected},
+		To:    scripts.StatusArchived,
+		Roles: []oAuth.Role{oAuth.RoleAdmin},
+	},
+}
+
+// normalizeRequestStatus treats requests created before the workflow existed as Pending
+func normalizeRequestStatus(status string) string {
+	if strings.TrimSpace(status) == "" {
+		return scripts.StatusPending
+	}
+	return status
+}
+
+// requestStatusFilter matches a stored status as read. Legacy requests have no status
+// field at all, so an empty status also matches a missing or null one.
+func requestStatusFilter(status string) interface{} {
+	if status == "" {
+		return bson.M{"$in": bson.A{"", nil}}
+	}
+	return status
+}
+
+func (t requestTransition) allowsFrom(status string) bool {
+	for _, from := range t.From {
+		if from == status {
+			return true
+		}
+	}
+	return false
+}
+
+// transitionRequest is the optional body accepted by the workflow endpoints
+type transitionRequest struct {
+	Note string `json:"note"`
+}
+
+// handleScriptRequestTransition moves a script request along the workflow
+// POST /api/script-request/review?id=xxx
+// POST /api/script-request/request-changes?id=xxx   {"note": "..."} (note required)
+// POST /api/script-request/resubmit?id=xxx
+// POST /api/script-request/reject?id=xxx            {"note": "..."} (note required)
+// POST /api/script-request/archive?id=xxx
+//
+// Approval is handled by handleApproveScriptRequest, which also creates the script.
+func handleScriptRequestTransition(w http.ResponseWriter, r *http.Request, collection *mongo.Collection, action string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	transition := requestTransitions[action]
+
+	if !callerHasAnyRole(r, transition.Roles...) {
+		respondWithError(w, http.StatusForbidden, "forbidden: requires one of roles "+joinRoleNames(transition.Roles))
+		return
+	}
+
+	objectID, ok := parseScriptRequestID(w, r)
+	if !ok {
+		return
+	}
+
+	var body transitionRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && err != io.EOF {
+		respondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	note := strings.TrimSpace(body.Note)
+	if transition.NoteRequired && note == "" {
+		respondWithError(w, http.StatusBadRequest, "A note is required to move a request to "+transition.To)
+		return
+	}
+
+	var current scripts.ScriptRequest
+	err := collection.FindOne(ctx, liveFilter(objectID)).Decode(&current)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			respondWithError(w, http.StatusNotFound, "Script request not found")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving script request")
+		return
+	}
+
+	from := normalizeRequestStatus(current.Status)
+	if !transition.allowsFrom(from) {
+		respondWithError(w, http.StatusConflict, "Cannot move a request from "+from+" to "+transition.To)
+		return
+	}
+
+	updated, err := applyRequestTransition(ctx, collection, objectID, current.Status, transition.To, note, callerIdentity(r), nil)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			respondWithError(w, http.StatusConflict, "Script request status changed concurrently, reload and retry")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Error updating script request status")
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, updated)
+}
+
+// applyRequestTransition records the move from storedStatus to "to" and returns the
+// updated request. The update only matches while the stored status is unchanged, so
+// concurrent transitions fail with mongo.ErrNoDocuments instead of both applying.
+// extra is merged into the $set document.
+func applyRequestTransition(ctx context.Context, collection *mongo.Collection, objectID primitive.ObjectID, storedStatus, to, note, by string, extra bson.M) (ScriptRequestWithID, error) {
+	now := time.Now().UTC().Format(time.RFC3339)
+	entry := scripts.StatusTransition{
+		From: normalizeRequestStatus(storedStatus),
+		To:   to,
+		Note: note,
+		By:   by,
+		At:   now,
+	}
+
+	set := bson.M{
+		"status":     to,
+		"updated_at": now,
+	}
+	if note != "" {
+		set["note"] = note
+	}
+	for key, value := range extra {
+		set[key] = value
+	}
+
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"_id": objectID, "status": requestStatusFilter(storedStatus), deletedAtField: notTrashed},
+		bson.M{"$set": set, "$push": bson.M{"history": entry}},
+	)
+	if err != nil {
+		return ScriptRequestWithID{}, err
+	}
+	if result.MatchedCount == 0 {
+		return ScriptRequestWithID{}, mongo.ErrNoDocuments
+	}
+
+	var rawDoc bson.M
+	if err := collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&rawDoc); err != nil {
+		return ScriptRequestWithID{}, err
+	}
+	return convertToScriptRequestWithID(rawDoc), nil
+}
+
+// handleGetScriptRequestHistory returns the status transitions of a request
+// GET /api/script-request/history?id=xxx
+func handleGetScriptRequestHistory(w http.ResponseWriter, r *http.Request, collection *mongo.Collection) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objectID, ok := parseScriptRequestID(w, r)
+	if !ok {
+		return
+	}
+
+	var req scripts.ScriptRequest
+	err := collection.FindOne(ctx, liveFilter(objectID)).Decode(&req)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			respondWithError(w, http.StatusNotFound, "Script request not found")
+			return
+		}
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving script request")
+		return
+	}
+
+	history := req.History
+	if history == nil {
+		history = []scripts.StatusTransition{}
+	}
+	respondWithJSON(w, http.StatusOK, map[string]interface{}{
+		"status":  normalizeRequestStatus(req.Status),
+		"history": history,
+	})
+}
+
+// parseScriptRequestID reads and validates the ?id= parameter, writing the error response on failure
+func parseScriptRequestID(w http.ResponseWriter, r *http.Request) (primitive.ObjectID, bool) {
+	reqID := r.URL.Query().Get("id")
+	if reqID == "" {
+		respondWithError(w, http.StatusBadRequest, "Script request ID is required")
+		return primitive.NilObjectID, false
+	}
+
+	objectID, err := primitive.ObjectIDFromHex(reqID)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid script request ID format")
+		return primitive.NilObjectID, false
+	}
+	return objectID, true
+}
+
+func joinRoleNames(roles []oAuth.Role) string {
+	names := make([]string, 0, len(roles))
+	for _, role := range roles {
+		names = append(names, string(role))
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,394 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"VCCwebsite/internal/model"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	scriptTextIndexName = "script_text_search"
+	maxSearchSnippets   = 3
+	snippetContext      = 60 // characters shown on each side of the first match
+)
+
+// scriptSearchFields are the script fields covered by the text index and their weights.
+// Array elements are addressed without an index, as in the index definition.
+var scriptSearchFields = []struct {
+	Path   string
+	Weight int
+}{
+	{"admin.reson_for_visit", 10},
+	{"admin.chief_concern", 10},
+	{"admin.diagnosis", 10},
+	{"patient.name", 5},
+	{"patient.visit_reason", 5},
+	{"admin.summory_of_story", 5},
+	{"admin.medical_event", 3},
+	{"admin.class", 3},
+	{"admin.author", 3},
+	{"admin.case_factors", 3},
+	{"admin.patient_demographic", 2},
+	{"patient.context", 2},
+	{"sp.opening_statement", 2},
+	{"sp.physical_chars", 1},
+	{"sp.current_ill_history.body_location", 2},
+	{"sp.current_ill_history.symptom_settings", 2},
+	{"sp.current_ill_history.symptom_timing", 2},
+	{"sp.current_ill_history.symptom_quality", 2},
+	{"sp.current_ill_history.associated_symptoms", 2},
+	{"sp.current_ill_history.radiation_of_symptoms", 2},
+	{"sp.current_ill_history.alleviating_factors", 2},
+	{"sp.current_ill_history.aggravating_factors", 2},
+	{"med_hist.allergies", 2},
+	{"med_hist.medications.name", 2},
+	{"med_hist.medications.brand", 2},
+	{"med_hist.medications.generic", 2},
+	{"med_hist.past_med_his.child_hood_illness", 1},
+	{"med_hist.past_med_his.illness_and_hospital", 1},
+	{"med_hist.past_med_his.surgeries", 1},
+	{"med_hist.past_med_his.psychiatric", 1},
+	{"med_hist.past_med_his.trauma", 1},
+	{"med_hist.family_hist.health_status", 1},
+	{"med_hist.family_hist.cause_of_death", 1},
+}
+
+// ensureScriptTextIndex creates the text index used by q= searches. A collection can
+// only have one text index, so an older definition is dropped and replaced.
+func ensureScriptTextIndex(ctx context.Context, collection *mongo.Collection) error {
+	keys := bson.D{}
+	weights := bson.D{}
+	for _, field := range scriptSearchFields {
+		keys = append(keys, bson.E{Key: field.Path, Value: "text"})
+		weights = append(weights, bson.E{Key: field.Path, Value: field.Weight})
+	}
+	index := mongo.IndexModel{
+		Keys: keys,
+		Options: options.Index().
+			SetName(scriptTextIndexName).
+			SetWeights(weights).
+			SetDefaultLanguage("english"),
+	}
+
+	_, err := collection.Indexes().CreateOne(ctx, index)
+	if !isIndexConflict(err) {
+		return err
+	}
+	if err := dropTextIndexes(ctx, collection); err != nil {
+		return err
+	}
+	_, err = collection.Indexes().CreateOne(ctx, index)
+	return err
+}
+
+// isIndexConflict reports an index that exists with different keys or options
+func isIndexConflict(err error) bool {
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) {
+		return cmdErr.Code == 85 || cmdErr.Code == 86 // IndexOptionsConflict, IndexKeySpecsConflict
+	}
+	return false
+}
+
+func dropTextIndexes(ctx context.Context, collection *mongo.Collection) error {
+	cursor, err := collection.Indexes().List(ctx)
+	if err != nil {
+		return err
+	}
+	var indexes []struct {
+		Name string `bson:"name"`
+		Key  bson.M `bson:"key"`
+	}
+	if err := cursor.All(ctx, &indexes); err != nil {
+		return err
+	}
+	for _, index := range indexes {
+		if index.Key["_fts"] == "text" {
+			if _, err := collection.Indexes().DropOne(ctx, index.Name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// containsFilter matches values containing s, case-insensitively. The input is quoted
+// so regex metacharacters in user input match literally.
+func containsFilter(s string) bson.M {
+	return bson.M{"$regex": regexp.QuoteMeta(s), "$options": "i"}
+}
+
+// searchResult is a script returned by a q= search with its relevance and the
+// passages that matched
+type searchResult struct {
+	DocumentWithID
+	Score    float64         `json:"score"`
+	Snippets []searchSnippet `json:"snippets"`
+}
+
+// searchSnippet is a passage of one field split into plain and matching fragments,
+// so clients can highlight matches without rendering HTML
+type searchSnippet struct {
+	Path      string            `json:"path"`
+	Fragments []snippetFragment `json:"fragments"`
+}
+
+type snippetFragment struct {
+	Text  string `json:"text"`
+	Match bool   `json:"match,omitempty"`
+}
+
+// searchDocuments answers GET /api/document?q=... using the text index. Results are
+// ranked by relevance unless sort= is given; the other filters still apply.
+func searchDocuments(ctx context.Context, w http.ResponseWriter, collection *mongo.Collection, params listParams, filter, projection bson.M, q string) {
+	filter["$text"] = bson.M{"$search": q}
+
+	total, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error searching documents")
+		return
+	}
+
+	score := bson.M{"$meta": "textScore"}
+	order := bson.D{}
+	for _, key := range params.Sort {
+		direction := 1
+		if key.Desc {
+			direction = -1
+		}
+		order = append(order, bson.E{Key: key.Field, Value: direction})
+	}
+	if len(order) == 0 {
+		order = append(order, bson.E{Key: "score", Value: score})
+	}
+	order = append(order, bson.E{Key: "_id", Value: 1})
+
+	fields := bson.M{"score": score}
+	for path, include := range projection {
+		fields[path] = include
+	}
+
+	// Text queries cannot use a collation, so findOptions is not used here
+	opts := options.Find().SetProjection(fields).SetSort(order)
+	if params.Limit > 0 {
+		opts.SetSkip(int64(params.Offset())).SetLimit(int64(params.Limit))
+	}
+
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error searching documents")
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var rawDocs []bson.M
+	if err := cursor.All(ctx, &rawDocs); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error decoding documents")
+		return
+	}
+
+	highlight := searchTermsPattern(q)
+	results := make([]searchResult, 0, len(rawDocs))
+	for _, rawDoc := range rawDocs {
+		result := searchResult{Snippets: []searchSnippet{}}
+		if s, ok := rawDoc["score"].(float64); ok {
+			result.Score = s
+		}
+		delete(rawDoc, "score")
+		result.DocumentWithID = convertToDocumentWithID(rawDoc)
+		if highlight != nil {
+			result.Snippets = searchSnippets(result.StandardizedScript, highlight)
+		}
+		results = append(results, result)
+	}
+
+	setListHeaders(w, params, total)
+
+	if projection != nil {
+		projected := make([]bson.M, 0, len(rawDocs))
+		for i, rawDoc := range rawDocs {
+			doc := projectedDocument(rawDoc)
+			doc["score"] = results[i].Score
+			doc["snippets"] = results[i].Snippets
+			projected = append(projected, doc)
+		}
+		respondWithJSON(w, http.StatusOK, projected)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, results)
+}
+
+// searchTermsPattern builds a case-insensitive pattern for highlighting the terms of a
+// text search. Quoted phrases are kept whole, negated terms are skipped and words are
+// reduced to a crude stem so "infections" also highlights "infection".
+func searchTermsPattern(q string) *regexp.Regexp {
+	var terms []string
+	for i, part := range strings.Split(q, `"`) {
+		if i%2 == 1 {
+			if phrase := strings.TrimSpace(part); phrase != "" {
+				terms = append(terms, regexp.QuoteMeta(phrase))
+			}
+			continue
+		}
+		for _, word := range strings.Fields(part) {
+			if strings.HasPrefix(word, "-") {
+				continue
+			}
+			word = strings.Trim(word, ".,;:!?()[]{}")
+			if word == "" {
+				continue
+			}
+			terms = append(terms, regexp.QuoteMeta(stemWord(word))+`\w*`)
+		}
+	}
+	if len(terms) == 0 {
+		return nil
+	}
+	return regexp.MustCompile(`(?i)\b(?:` + strings.Join(terms, "|") + `)`)
+}
+
+func stemWord(word string) string {
+	lower := strings.ToLower(word)
+	for _, suffix := range []string{"ing", "es", "ed", "s"} {
+		if len(lower) > len(suffix)+3 && strings.HasSuffix(lower, suffix) {
+			return word[:len(word)-len(suffix)]
+		}
+	}
+	return word
+}
+
+// searchSnippets returns up to maxSearchSnippets passages of indexed fields that
+// match the pattern, highest weighted fields first
+func searchSnippets(doc scripts.StandardizedScript, pattern *regexp.Regexp) []searchSnippet {
+	weights := make(map[string]int, len(scriptSearchFields))
+	for _, field := range scriptSearchFields {
+		weights[field.Path] = field.Weight
+	}
+
+	var tree interface{}
+	raw, _ := json.Marshal(doc)
+	_ = json.Unmarshal(raw, &tree)
+
+	type candidate struct {
+		snippet searchSnippet
+		weight  int
+	}
+	var candidates []candidate
+	var walk func(node interface{}, path, field string)
+	walk = func(node interface{}, path, field string) {
+		switch v := node.(type) {
+		case map[string]interface{}:
+			for key, child := range v {
+				childPath, childField := key, key
+				if path != "" {
+					childPath, childField = path+"."+key, field+"."+key
+				}
+				walk(child, childPath, childField)
+			}
+		case []interface{}:
+			for i, child := range v {
+				walk(child, fmt.Sprintf("%s[%d]", path, i), field)
+			}
+		case string:
+			weight, indexed := weights[field]
+			if !indexed {
+				return
+			}
+			if fragments := snippetFragments(v, pattern); fragments != nil {
+				candidates = append(candidates, candidate{searchSnippet{Path: path, Fragments: fragments}, weight})
+			}
+		}
+	}
+	walk(tree, "", "")
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].weight != candidates[j].weight {
+			return candidates[i].weight > candidates[j].weight
+		}
+		return candidates[i].snippet.Path < candidates[j].snippet.Path
+	})
+	snippets := []searchSnippet{}
+	for _, c := range candidates {
+		if len(snippets) == maxSearchSnippets {
+			break
+		}
+		snippets = append(snippets, c.snippet)
+	}
+	return snippets
+}
+
+// snippetFragments cuts a window around the first match of text and splits it into
+// fragments, or returns nil when nothing matches
+func snippetFragments(text string, pattern *regexp.Regexp) []snippetFragment {
+	first := pattern.FindStringIndex(text)
+	if first == nil {
+		return nil
+	}
+
+	start := backRunes(text, first[0], snippetContext)
+	end := forwardRunes(text, first[1], snippetContext)
+	window := text[start:end]
+
+	var fragments []snippetFragment
+	if start > 0 {
+		fragments = append(fragments, snippetFragment{Text: "…"})
+	}
+	last := 0
+	for _, m := range pattern.FindAllStringIndex(window, -1) {
+		if m[0] > last {
+			fragments = append(fragments, snippetFragment{Text: window[last:m[0]]})
+		}
+		fragments = append(fragments, snippetFragment{Text: window[m[0]:m[1]], Match: true})
+		last = m[1]
+	}
+	if last < len(window) {
+		fragments = append(fragments, snippetFragment{Text: window[last:]})
+	}
+	if end < len(text) {
+		fragments = append(fragments, snippetFragment{Text: "…"})
+	}
+	return mergeEllipses(fragments)
+}
+
+// mergeEllipses folds the leading and trailing "…" into their neighbouring plain fragments
+func mergeEllipses(fragments []snippetFragment) []snippetFragment {
+	if len(fragments) > 1 && fragments[0].Text == "…" && !fragments[1].Match {
+		fragments[1].Text = "…" + fragments[1].Text
+		fragments = fragments[1:]
+	}
+	if n := len(fragments); n > 1 && fragments[n-1].Text == "…" && !fragments[n-2].Match {
+		fragments[n-2].Text += "…"
+		fragments = fragments[:n-1]
+	}
+	return fragments
+}
+
+// backRunes moves n runes back from byte offset i
+func backRunes(s string, i, n int) int {
+	for ; n > 0 && i > 0; n-- {
+		_, size := utf8.DecodeLastRuneInString(s[:i])
+		i -= size
+	}
+	return i
+}
+
+// forwardRunes moves n runes forward from byte offset i
+func forwardRunes(s string, i, n int) int {
+	for ; n > 0 && i < len(s); n-- {
+		_, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+	}
+	return i
+}
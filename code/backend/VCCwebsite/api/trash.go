@@ -0,0 +1,422 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"VCCwebsite/internal/artifactstore"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Trashed scripts and requests keep their record with these two fields set. They are
+// not part of the models, so PUT and PATCH bodies cannot touch them.
+const (
+	deletedAtField = "deleted_at" // BSON date the record was moved to the trash
+	deletedByField = "deleted_by" // caller identity of whoever deleted it
+)
+
+// defaultTrashRetention applies when TRASH_RETENTION_DAYS is unset
+const defaultTrashRetention = 30 * 24 * time.Hour
+
+// trashPurgeInterval is how often StartTrashPurger looks for expired records
+const trashPurgeInterval = time.Hour
+
+// notTrashed matches records that are not in the trash
+var notTrashed = bson.M{"$exists": false}
+
+// liveFilter matches the record with the given ID unless it is in the trash. Handlers
+// use it so a trashed record answers 404 everywhere except the trash endpoints.
+func liveFilter(id primitive.ObjectID) bson.M {
+	return bson.M{"_id": id, deletedAtField: notTrashed}
+}
+
+// TrashRetentionFromEnv reads TRASH_RETENTION_DAYS, the number of days a deleted
+// script or request stays restorable. 0 keeps trashed records forever.
+func TrashRetentionFromEnv() (time.Duration, error) {
+	raw := os.Getenv("TRASH_RETENTION_DAYS")
+	if raw == "" {
+		return defaultTrashRetention, nil
+	}
+	days, err := strconv.Atoi(raw)
+	if err != nil || days < 0 {
+		return 0, fmt.Errorf("TRASH_RETENTION_DAYS must be a non-negative number of days, got %q", raw)
+	}
+	return time.Duration(days) * 24 * time.Hour, nil
+}
+
+// trashEntry is one record in the GET /api/trash listing
+type trashEntry struct {
+	ID        string     `json:"id"`
+	Title     string     `json:"title"`
+	DeletedAt time.Time  `json:"deleted_at"`
+	DeletedBy string     `json:"deleted_by,omitempty"`
+	PurgeAt   *time.Time `json:"purge_at,omitempty"`
+}
+
+// trashKinds are the collections that have a trash, keyed by the type= name
+var trashKinds = map[string]struct {
+	Collection string
+	TitleField string
+	NotFound   string
+}{
+	"document": {"scripts", "admin.reson_for_visit", "Document not found in trash"},
+	"request":  {"script_requests", "reason_for_visit", "Script request not found in trash"},
+}
+
+// TrashHandler serves the trash bin shared by scripts and script requests
+// GET    /api/trash                      - everything in the trash, newest first
+// GET    /api/trash?type=document|request
+// DELETE /api/trash?type=document&id=xxx - purge one record now instead of waiting
+func TrashHandler(client *mongo.Client, store artifactstore.Store, retention time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if client == nil {
+			respondWithError(w, http.StatusServiceUnavailable, "Database connection not available")
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			handleGetTrash(w, r, client, retention)
+		case http.MethodDelete:
+			handlePurgeTrashEntry(w, r, client, store)
+		default:
+			respondWithError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+	}
+}
+
+func handleGetTrash(w http.ResponseWriter, r *http.Request, client *mongo.Client, retention time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	kinds := []string{"document", "request"}
+	if kind := r.URL.Query().Get("type"); kind != "" {
+		if _, ok := trashKinds[kind]; !ok {
+			respondWithError(w, http.StatusBadRequest, "type must be document or request")
+			return
+		}
+		kinds = []string{kind}
+	}
+
+	response := map[string][]trashEntry{}
+	for _, kind := range kinds {
+		entries, err := listTrash(ctx, client, kind, retention)
+		if err != nil {
+			respondWithError(w, http.StatusInternalServerError, "Error retrieving trash")
+			return
+		}
+		response[kind+"s"] = entries
+	}
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// listTrash returns the trashed records of one kind, most recently deleted first
+func listTrash(ctx context.Context, client *mongo.Client, kind string, retention time.Duration) ([]trashEntry, error) {
+	spec := trashKinds[kind]
+	collection := client.Database("vccwebsite").Collection(spec.Collection)
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: deletedAtField, Value: -1}, {Key: "_id", Value: 1}}).
+		SetProjection(bson.M{deletedAtField: 1, deletedByField: 1, spec.TitleField: 1})
+	cursor, err := collection.Find(ctx, bson.M{deletedAtField: bson.M{"$exists": true}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	entries := []trashEntry{}
+	for cursor.Next(ctx) {
+		raw := cursor.Current
+		entry := trashEntry{}
+		if id, ok := raw.Lookup("_id").ObjectIDOK(); ok {
+			entry.ID = id.Hex()
+		}
+		if title, ok := raw.Lookup(strings.Split(spec.TitleField, ".")...).StringValueOK(); ok {
+			entry.Title = title
+		}
+		if at, ok := raw.Lookup(deletedAtField).DateTimeOK(); ok {
+			entry.DeletedAt = time.UnixMilli(at).UTC()
+		}
+		if by, ok := raw.Lookup(deletedByField).StringValueOK(); ok {
+			entry.DeletedBy = by
+		}
+		if retention > 0 {
+			purgeAt := entry.DeletedAt.Add(retention)
+			entry.PurgeAt = &purgeAt
+		}
+		entries = append(entries, entry)
+	}
+	return entries, cursor.Err()
+}
+
+// handlePurgeTrashEntry permanently removes one trashed record
+func handlePurgeTrashEntry(w http.ResponseWriter, r *http.Request, client *mongo.Client, store artifactstore.Store) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	kind := r.URL.Query().Get("type")
+	spec, ok := trashKinds[kind]
+	if !ok {
+		respondWithError(w, http.StatusBadRequest, "type must be document or request")
+		return
+	}
+	id := r.URL.Query().Get("id")
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid ID format")
+		return
+	}
+
+	database := client.Database("vccwebsite")
+	refs, err := findReferences(ctx, database, kind, objectID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error checking references")
+		return
+	}
+	if blocking := blockingReferences(refs); len(blocking) > 0 {
+		respondWithJSON(w, http.StatusConflict, map[string]interface{}{
+			"error":      "Record is still referenced",
+			"references": blocking,
+		})
+		return
+	}
+
+	purged, err := purgeTrashed(ctx, database, store, kind, bson.M{"_id": objectID})
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error purging trash")
+		return
+	}
+	if purged == 0 {
+		respondWithError(w, http.StatusNotFound, spec.NotFound)
+		return
+	}
+
+	respondWithJSON(w, http.StatusOK, map[string]string{
+		"message": "Permanently deleted",
+		"id":      id,
+	})
+}
+
+// moveToTrash marks a live record as deleted by the caller. It reports false when no
+// live record has that ID.
+func moveToTrash(ctx context.Context, collection *mongo.Collection, id primitive.ObjectID, by string) (bool, error) {
+	set := bson.M{deletedAtField: time.Now().UTC()}
+	if by != "" {
+		set[deletedByField] = by
+	}
+	result, err := collection.UpdateOne(ctx, liveFilter(id), bson.M{"$set": set})
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// restoreFromTrash clears the deleted marker of a trashed record. It reports false
+// when the record is not in the trash.
+func restoreFromTrash(ctx context.Context, collection *mongo.Collection, id primitive.ObjectID) (bool, error) {
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"_id": id, deletedAtField: bson.M{"$exists": true}},
+		bson.M{
+			"$unset": bson.M{deletedAtField: "", deletedByField: ""},
+			"$set":   bson.M{"updated_at": time.Now().UTC().Format(time.RFC3339)},
+		},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// handleUndeleteDocument takes a script back out of the trash
+// POST /api/document/undelete?id=xxx
+func handleUndeleteDocument(w http.ResponseWriter, r *http.Request, collection *mongo.Collection) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	docID := r.URL.Query().Get("id")
+	if docID == "" {
+		respondWithError(w, http.StatusBadRequest, "Document ID is required")
+		return
+	}
+	objectID, err := primitive.ObjectIDFromHex(docID)
+	if err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid document ID format")
+		return
+	}
+
+	restored, err := restoreFromTrash(ctx, collection, objectID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error restoring document")
+		return
+	}
+	if !restored {
+		respondWithError(w, http.StatusNotFound, trashKinds["document"].NotFound)
+		return
+	}
+
+	var rawDoc bson.M
+	if err := collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&rawDoc); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving restored document")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, convertToDocumentWithID(rawDoc))
+}
+
+// handleUndeleteScriptRequest takes a script request back out of the trash
+// POST /api/script-request/undelete?id=xxx
+func handleUndeleteScriptRequest(w http.ResponseWriter, r *http.Request, collection *mongo.Collection) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objectID, ok := parseScriptRequestID(w, r)
+	if !ok {
+		return
+	}
+
+	restored, err := restoreFromTrash(ctx, collection, objectID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error restoring script request")
+		return
+	}
+	if !restored {
+		respondWithError(w, http.StatusNotFound, trashKinds["request"].NotFound)
+		return
+	}
+
+	var rawDoc bson.M
+	if err := collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&rawDoc); err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving restored script request")
+		return
+	}
+	respondWithJSON(w, http.StatusOK, convertToScriptRequestWithID(rawDoc))
+}
+
+// purgeTrashed permanently deletes the trashed records of one kind matching filter.
+// Records referring to a purged record are cascaded or unlinked per referenceKinds,
+// a script's version counter goes with it, and artifacts that only the purged record
+// used are deleted.
+func purgeTrashed(ctx context.Context, database *mongo.Database, store artifactstore.Store, kind string, filter bson.M) (int, error) {
+	collection := database.Collection(trashKinds[kind].Collection)
+
+	match := bson.M{deletedAtField: bson.M{"$exists": true}}
+	for key, value := range filter {
+		match[key] = value
+	}
+
+	cursor, err := collection.Find(ctx, match, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return 0, err
+	}
+	var expired []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &expired); err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, record := range expired {
+		artifacts, err := ownedArtifacts(ctx, database, kind, record.ID)
+		if err != nil {
+			return purged, err
+		}
+
+		// Records still needed elsewhere stay in the trash until the reference is gone
+		refs, err := findReferences(ctx, database, kind, record.ID)
+		if err != nil {
+			return purged, err
+		}
+		if blocking := blockingReferences(refs); len(blocking) > 0 {
+			log.Printf("trash: keeping %s %s, still referenced by %d record(s)", kind, record.ID.Hex(), len(blocking))
+			continue
+		}
+
+		// Re-check the marker so a record undeleted meanwhile survives
+		result, err := collection.DeleteOne(ctx, bson.M{"_id": record.ID, deletedAtField: bson.M{"$exists": true}})
+		if err != nil {
+			return purged, err
+		}
+		if result.DeletedCount == 0 {
+			continue
+		}
+		purged++
+
+		if kind == "document" {
+			if _, err := database.Collection(versionCountersCollection).DeleteOne(ctx, bson.M{"_id": record.ID}); err != nil {
+				return purged, err
+			}
+		}
+		if err := releaseReferences(ctx, database, kind, record.ID); err != nil {
+			return purged, err
+		}
+		if _, err := deleteOrphanedArtifacts(ctx, database, store, artifacts); err != nil {
+			return purged, err
+		}
+	}
+	return purged, nil
+}
+
+// PurgeExpiredTrash deletes every script and request that has been in the trash for
+// longer than retention and returns how many records were removed
+func PurgeExpiredTrash(ctx context.Context, client *mongo.Client, store artifactstore.Store, retention time.Duration) (int, error) {
+	if client == nil || retention <= 0 {
+		return 0, nil
+	}
+	database := client.Database("vccwebsite")
+	cutoff := bson.M{deletedAtField: bson.M{"$lte": time.Now().UTC().Add(-retention)}}
+
+	total := 0
+	for _, kind := range []string{"document", "request"} {
+		purged, err := purgeTrashed(ctx, database, store, kind, cutoff)
+		total += purged
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// StartTrashPurger runs PurgeExpiredTrash now and then every trashPurgeInterval until
+// ctx is cancelled
+func StartTrashPurger(ctx context.Context, client *mongo.Client, store artifactstore.Store, retention time.Duration) {
+	if client == nil || retention <= 0 {
+		return
+	}
+	purge := func() {
+		pctx, cancel := context.WithTimeout(ctx, time.Minute)
+		defer cancel()
+		purged, err := PurgeExpiredTrash(pctx, client, store, retention)
+		if err != nil {
+			log.Printf("trash purge failed: %v", err)
+		}
+		if purged > 0 {
+			log.Printf("trash purge removed %d record(s) older than %s", purged, retention)
+		}
+	}
+
+	go func() {
+		purge()
+		ticker := time.NewTicker(trashPurgeInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				purge()
+			}
+		}
+	}()
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"VCCwebsite/internal/model"
+	"VCCwebsite/internal/validation"
+)
+
+// respondWithValidationError writes 422 with the field errors of a failed validation
+// so the UI can attach each message to its form field
+func respondWithValidationError(w http.ResponseWriter, err error) {
+	var fieldErrs validation.Errors
+	if !errors.As(err, &fieldErrs) {
+		respondWithError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	respondWithJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+		"error":  "Validation failed",
+		"errors": fieldErrs,
+	})
+}
+
+// setVitalsWarnings reports out-of-normal vitals of a saved script in the
+// X-Vitals-Warnings header; the save itself is not affected. Header values are
+// ASCII, so unit symbols lose their degree sign ("38.5 C").
+func setVitalsWarnings(w http.ResponseWriter, s *scripts.StandardizedScript) {
+	warnings := validation.ScriptVitals(s).Warnings
+	if len(warnings) == 0 {
+		return
+	}
+	msgs := make([]string, 0, len(warnings))
+	for _, fe := range warnings {
+		msgs = append(msgs, fe.Path+": "+strings.ReplaceAll(fe.Message, "°", ""))
+	}
+	w.Header().Set("X-Vitals-Warnings", strings.Join(msgs, "; "))
+}
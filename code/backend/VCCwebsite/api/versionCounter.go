@@ -0,0 +1,85 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	versionCountersCollection = "scripts_version_counters"
+	maxVersionAttempts        = 5
+)
+
+// errVersionConflict is returned when another writer claimed the expected version first
+var errVersionConflict = errors.New("document version changed concurrently")
+
+// versionCounter is the per-document sequence stored in scripts_version_counters
+type versionCounter struct {
+	DocumentID primitive.ObjectID `bson:"_id"`
+	Seq        int                `bson:"seq"`
+}
+
+// allocateVersionNumber atomically reserves the next version number of a document.
+// When expected is >= 0 the counter only advances if it still equals expected.
+func allocateVersionNumber(ctx context.Context, versionsCollection *mongo.Collection, docID primitive.ObjectID, expected int) (int, error) {
+	counters := versionsCollection.Database().Collection(versionCountersCollection)
+
+	// Bring the counter up to the stored versions; covers documents versioned
+	// before the counter existed and versions inserted directly (approvals).
+	latest, err := latestStoredVersion(ctx, versionsCollection, docID)
+	if err != nil {
+		return 0, err
+	}
+	_, err = counters.UpdateOne(ctx,
+		bson.M{"_id": docID},
+		bson.M{"$max": bson.M{"seq": latest}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		return 0, err
+	}
+
+	filter := bson.M{"_id": docID}
+	if expected >= 0 {
+		filter["seq"] = expected
+	}
+
+	var counter versionCounter
+	err = counters.FindOneAndUpdate(ctx, filter,
+		bson.M{"$inc": bson.M{"seq": 1}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&counter)
+	if err == mongo.ErrNoDocuments {
+		return 0, errVersionConflict
+	}
+	if err != nil {
+		return 0, err
+	}
+	return counter.Seq, nil
+}
+
+// releaseVersionNumber hands back a reserved number whose version could not be written,
+// keeping the history gapless. It is a no-op when another writer has moved on.
+func releaseVersionNumber(ctx context.Context, versionsCollection *mongo.Collection, docID primitive.ObjectID, versionNumber int) {
+	counters := versionsCollection.Database().Collection(versionCountersCollection)
+	_, _ = counters.UpdateOne(ctx,
+		bson.M{"_id": docID, "seq": versionNumber},
+		bson.M{"$inc": bson.M{"seq": -1}},
+	)
+}
+
+// respondWithStaleVersion answers 412 with the version that won the race
+func respondWithStaleVersion(ctx context.Context, w http.ResponseWriter, versionsCollection *mongo.Collection, docID primitive.ObjectID) {
+	current, err := currentVersionNumber(ctx, versionsCollection, docID)
+	if err != nil {
+		respondWithError(w, http.StatusInternalServerError, "Error retrieving document version")
+		return
+	}
+	respondWithPreconditionFailed(w, current)
+}
@@ -0,0 +1,182 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"VCCwebsite/internal/measurement"
+	"VCCwebsite/internal/model"
+)
+
+// Orthostatic changes from lying (or sitting) to standing that count as positive
+const (
+	orthostaticSystolicDrop  = 20
+	orthostaticDiastolicDrop = 10
+	orthostaticPulseRise     = 30
+)
+
+// vitalsResponse is the payload of GET /api/document/vitals: the stored readings of a
+// phase plus the values derived from them
+type vitalsResponse struct {
+	Phase         string `json:"phase"`
+	OffsetMinutes int    `json:"offset_minutes"`
+	Trigger       string `json:"trigger,omitempty"`
+	scripts.VitalSigns
+	Derived derivedVitals `json:"derived"`
+}
+
+// derivedVitals are computed from the stored readings and never stored themselves
+type derivedVitals struct {
+	BMI         float64            `json:"bmi,omitempty"`
+	BMICategory string             `json:"bmi_category,omitempty"`
+	Pain        uint8              `json:"pain"`
+	Orthostatic *orthostaticChange `json:"orthostatic,omitempty"`
+	Display     map[string]string  `json:"display"`
+}
+
+// orthostaticChange compares the standing readings with the lying (or sitting) ones
+type orthostaticChange struct {
+	From          string `json:"from"`
+	SystolicDrop  int16  `json:"systolic_drop"`
+	DiastolicDrop int16  `json:"diastolic_drop"`
+	HeartRateRise int16  `json:"heart_rate_rise"`
+	Positive      bool   `json:"positive"`
+}
+
+// vitalsPhaseState is a phase of the vitals timeline with the path it is stored at
+type vitalsPhaseState struct {
+	scripts.VitalsPhase
+	path string
+}
+
+// vitalsPhases returns the baseline vitals followed by the timeline, ordered by offset
+func vitalsPhases(doc scripts.StandardizedScript) []vitalsPhaseState {
+	phases := []vitalsPhaseState{{
+		VitalsPhase: scripts.VitalsPhase{Phase: scripts.BaselinePhase, Vitals: doc.Patient.Vitals},
+		path:        "patient.vitals",
+	}}
+	for i, phase := range doc.Patient.VitalsTimeline {
+		phases = append(phases, vitalsPhaseState{
+			VitalsPhase: phase,
+			path:        fmt.Sprintf("patient.vitals_timeline[%d].vitals", i),
+		})
+	}
+	sort.SliceStable(phases, func(i, j int) bool {
+		return phases[i].OffsetMinutes < phases[j].OffsetMinutes
+	})
+	return phases
+}
+
+// findVitalsPhase resolves the phase query parameter: a phase name, or a minute offset
+// selecting the phase in effect at that minute. An empty query selects the baseline.
+func findVitalsPhase(phases []vitalsPhaseState, query string) (vitalsPhaseState, bool) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		query = scripts.BaselinePhase
+	}
+	if minute, err := strconv.Atoi(query); err == nil {
+		if minute < 0 {
+			return vitalsPhaseState{}, false
+		}
+		current := phases[0]
+		for _, phase := range phases {
+			if phase.OffsetMinutes <= minute {
+				current = phase
+			}
+		}
+		return current, true
+	}
+	for _, phase := range phases {
+		if strings.EqualFold(phase.Phase, query) {
+			return phase, true
+		}
+	}
+	return vitalsPhaseState{}, false
+}
+
+// vitalsView builds the vitals payload of one phase of a script
+func vitalsView(doc scripts.StandardizedScript, phase vitalsPhaseState) vitalsResponse {
+	v := phase.Vitals
+	derived := derivedVitals{
+		Pain:    doc.SP.CurrentIllHistory.Pain,
+		Display: vitalsDisplay(v),
+	}
+
+	if bmi := measurement.BMI(v.Weight, v.Height); bmi > 0 {
+		derived.BMI = math.Round(bmi*10) / 10
+		// WHO categories only apply to adults
+		if age := doc.Patient.Age; age == nil || *age >= 18 {
+			derived.BMICategory = measurement.BMICategory(bmi)
+		}
+		derived.Display["bmi"] = fmt.Sprintf("%.1f kg/m²", bmi)
+	}
+
+	derived.Orthostatic = orthostaticFor(v)
+	return vitalsResponse{
+		Phase:         phase.Phase,
+		OffsetMinutes: phase.OffsetMinutes,
+		Trigger:       phase.Trigger,
+		VitalSigns:    v,
+		Derived:       derived,
+	}
+}
+
+func orthostaticFor(v scripts.VitalSigns) *orthostaticChange {
+	if v.Orthostatic == nil {
+		return nil
+	}
+	standing := v.Orthostatic.Standing
+	base, from := v.Orthostatic.Lying, "lying"
+	if base.Pressure.Top == 0 {
+		base, from = v.Orthostatic.Sitting, "sitting"
+	}
+	if base.Pressure.Top == 0 || standing.Pressure.Top == 0 {
+		return nil
+	}
+
+	change := &orthostaticChange{
+		From:          from,
+		SystolicDrop:  base.Pressure.Top - standing.Pressure.Top,
+		DiastolicDrop: base.Pressure.Bottom - standing.Pressure.Bottom,
+	}
+	if base.HeartRate > 0 && standing.HeartRate > 0 {
+		change.HeartRateRise = standing.HeartRate - base.HeartRate
+	}
+	change.Positive = change.SystolicDrop >= orthostaticSystolicDrop ||
+		change.DiastolicDrop >= orthostaticDiastolicDrop ||
+		change.HeartRateRise >= orthostaticPulseRise
+	return change
+}
+
+// vitalsDisplay formats the entered readings the way door notes print them
+func vitalsDisplay(v scripts.VitalSigns) map[string]string {
+	display := map[string]string{}
+	if v.HeartRate > 0 {
+		display["heart_rate"] = fmt.Sprintf("%d beats/min", v.HeartRate)
+	}
+	if v.Respirations > 0 {
+		display["respirations"] = fmt.Sprintf("%d breaths/min", v.Respirations)
+	}
+	if v.Pressure.Top > 0 {
+		display["pressure"] = fmt.Sprintf("%d/%d mmHg", v.Pressure.Top, v.Pressure.Bottom)
+	}
+	if v.BloodOxygen > 0 {
+		display["blood_oxygen"] = fmt.Sprintf("%d%%", v.BloodOxygen)
+	}
+	if v.Temp.Reading != 0 {
+		display["temp"] = v.Temp.Display()
+	}
+	if v.Weight.Reading > 0 {
+		display["weight"] = v.Weight.Display()
+	}
+	if v.Height.Reading > 0 {
+		display["height"] = v.Height.Display()
+	}
+	if v.Glucose.Reading > 0 {
+		display["glucose"] = v.Glucose.Display()
+	}
+	return display
+}
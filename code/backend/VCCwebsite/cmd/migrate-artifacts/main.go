@@ -0,0 +1,132 @@
+// Command migrate-artifacts copies every artifact from one store to another, keeping
+// IDs, names, content types and upload times so scripts and requests keep working
+// once ARTIFACT_STORE points at the new backend.
+//
+//	go run ./cmd/migrate-artifacts -to local
+//	go run ./cmd/migrate-artifacts -from gridfs -to s3 -dry-run
+//
+// Stores are configured from the same environment variables as the service (see
+// artifactstore.ByKind). Artifacts already present in the target with the same size
+// are skipped, so an interrupted run can simply be started again. The source is never
+// modified.
+package main
+
+import (
+	"VCCwebsite/internal/artifactstore"
+	"VCCwebsite/internal/db"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func main() {
+	from := flag.String("from", "gridfs", "source store: gridfs, local or s3")
+	to := flag.String("to", "", "target store: gridfs, local or s3")
+	dryRun := flag.Bool("dry-run", false, "list what would be copied without writing")
+	flag.Parse()
+
+	if *to == "" {
+		log.Fatal("-to is required")
+	}
+	if strings.EqualFold(*from, *to) {
+		log.Fatal("-from and -to name the same store")
+	}
+
+	ctx := context.Background()
+
+	var client *mongo.Client
+	if usesGridFS(*from) || usesGridFS(*to) {
+		cctx, cancel := context.WithTimeout(ctx, 15*time.Second)
+		defer cancel()
+		var err error
+		client, err = db.Connect(cctx)
+		if err != nil {
+			log.Fatalf("mongodb connect failed: %v", err)
+		}
+		defer func() {
+			if err := db.MustDisconnect(context.Background(), client); err != nil {
+				log.Printf("error disconnecting mongo client: %v", err)
+			}
+		}()
+	}
+
+	source, err := artifactstore.ByKind(*from, client)
+	if err != nil {
+		log.Fatalf("source store: %v", err)
+	}
+	target, err := artifactstore.ByKind(*to, client)
+	if err != nil {
+		log.Fatalf("target store: %v", err)
+	}
+
+	var copied, skipped, failed int
+	var bytes int64
+	err = source.Walk(ctx, func(info artifactstore.Info) error {
+		if existing, err := target.Stat(ctx, info.ID); err == nil && existing.Size == info.Size {
+			skipped++
+			return nil
+		} else if err != nil && err != artifactstore.ErrNotFound {
+			return fmt.Errorf("checking %s in target: %w", info.ID, err)
+		}
+
+		if *dryRun {
+			fmt.Printf("would copy %s %q (%d bytes)\n", info.ID, info.Name, info.Size)
+			copied++
+			bytes += info.Size
+			return nil
+		}
+
+		if err := copyArtifact(ctx, source, target, info); err != nil {
+			log.Printf("copying %s failed: %v", info.ID, err)
+			failed++
+			return nil
+		}
+		copied++
+		bytes += info.Size
+		return nil
+	})
+	if err != nil {
+		log.Fatalf("migration stopped: %v", err)
+	}
+
+	verb := "copied"
+	if *dryRun {
+		verb = "would copy"
+	}
+	fmt.Printf("%s → %s: %s %d artifact(s) (%d bytes), %d already present, %d failed\n",
+		source.Kind(), target.Kind(), verb, copied, bytes, skipped, failed)
+	if failed > 0 {
+		log.Fatal("some artifacts were not copied; run again to retry them")
+	}
+}
+
+// copyArtifact streams one artifact from source to target under the same ID
+func copyArtifact(ctx context.Context, source, target artifactstore.Store, info artifactstore.Info) error {
+	cctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	content, info, err := source.Open(cctx, info.ID)
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+
+	stored, err := target.Put(cctx, info, content)
+	if err != nil {
+		return err
+	}
+	if stored.Size != info.Size {
+		return fmt.Errorf("size mismatch: source %d bytes, target %d bytes", info.Size, stored.Size)
+	}
+	return nil
+}
+
+func usesGridFS(kind string) bool {
+	kind = strings.ToLower(strings.TrimSpace(kind))
+	return kind == "" || kind == "gridfs"
+}
@@ -3,14 +3,18 @@ package main
 import (
 	"VCCwebsite/api"
 	"VCCwebsite/internal/actorDB"
+	"VCCwebsite/internal/artifactstore"
 	"VCCwebsite/internal/db"
 	"VCCwebsite/internal/oAuth"
+	"VCCwebsite/internal/validation"
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 )
 
@@ -30,7 +34,8 @@ func corsMiddleware(next http.Handler) http.Handler {
 			w.Header().Set("Access-Control-Allow-Origin", "*")
 		}
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Accept")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, Accept, If-Match, If-None-Match, If-Modified-Since, If-Range, Range")
+		w.Header().Set("Access-Control-Expose-Headers", "ETag, X-Vitals-Warnings, X-Total-Count, X-Page, X-Per-Page, Content-Range, Content-Disposition, Last-Modified")
 		w.Header().Set("Access-Control-Allow-Credentials", "true")
 		w.Header().Set("Access-Control-Max-Age", "3600")
 
@@ -102,6 +107,18 @@ func main() {
 	}
 	if mongoClient != nil {
 		log.Println("Connected to MongoDB")
+		mctx, mcancel := context.WithTimeout(ctx, 10*time.Minute)
+		err := api.MigrateLegacyFieldNames(mctx, mongoClient)
+		mcancel()
+		if err != nil {
+			log.Fatalf("mongodb field name migration failed: %v", err)
+		}
+		ictx, icancel := context.WithTimeout(ctx, 2*time.Minute)
+		err = api.EnsureIndexes(ictx, mongoClient)
+		icancel()
+		if err != nil {
+			log.Fatalf("mongodb index setup failed: %v", err)
+		}
 		defer func() {
 			if err := db.MustDisconnect(context.Background(), mongoClient); err != nil {
 				log.Printf("error disconnecting mongo client: %v", err)
@@ -111,8 +128,34 @@ func main() {
 		log.Println("Running without MongoDB connection")
 	}
 
+	// Artifacts stay downloadable without MongoDB unless they are kept in GridFS
+	artifactStore, err := artifactstore.FromEnv(mongoClient)
+	if err == artifactstore.ErrNoDatabase {
+		log.Printf("artifact storage unavailable: %v", err)
+	} else if err != nil {
+		log.Fatalf("artifact storage setup failed: %v", err)
+	} else {
+		log.Printf("Artifact storage: %s", artifactStore.Kind())
+	}
+
+	validation.LearnerLevels = validation.LearnerLevelsFromEnv()
+	log.Printf("Learner levels: %s", strings.Join(validation.LearnerLevels, ", "))
+
+	trashRetention, err := api.TrashRetentionFromEnv()
+	if err != nil {
+		log.Fatalf("trash configuration failed: %v", err)
+	}
+	api.StartTrashPurger(ctx, mongoClient, artifactStore, trashRetention)
+
+	artifactGrace, err := api.ArtifactGraceFromEnv()
+	if err != nil {
+		log.Fatalf("artifact sweeper configuration failed: %v", err)
+	}
+	api.StartArtifactSweeper(ctx, mongoClient, artifactStore, artifactGrace)
+
 	// Initialize Okta authentication middleware (optional)
 	var authMiddleware *oAuth.CachedAuthMiddleware
+	var roleResolver *oAuth.RoleResolver
 	oktaDomain := os.Getenv("OKTA_DOMAIN")
 	oktaIssuer := os.Getenv("OKTA_ISSUER")
 	oktaAudience := os.Getenv("OKTA_AUDIENCE")
@@ -124,10 +167,55 @@ func main() {
 			Audience: oktaAudience,
 		}
 		authMiddleware = oAuth.NewCachedAuthMiddleware(oktaConfig, 1*time.Hour)
+
+		roleResolver, err = oAuth.NewRoleResolverFromEnv()
+		if err != nil {
+			log.Fatalf("role configuration failed: %v", err)
+		}
 	} else {
 		log.Println("Okta authentication disabled (missing OKTA_DOMAIN, OKTA_ISSUER, or OKTA_AUDIENCE)")
 	}
 
+	// protect wraps a handler with authentication and the given role policy.
+	// When Okta is disabled the handler is returned unchanged.
+	protect := func(h http.Handler, policy oAuth.Policy) http.Handler {
+		if authMiddleware == nil {
+			return h
+		}
+		return authMiddleware.Middleware(roleResolver.Authorize(policy, h))
+	}
+
+	// ── Route policies ────────────────────────────────────────────────────────
+	editors := []oAuth.Role{oAuth.RoleAdmin, oAuth.RoleAuthor}
+	readOnly := oAuth.ReadOnly(editors...)
+	documentPolicy := oAuth.Policy{
+		http.MethodGet:    oAuth.AllRoles,
+		http.MethodPost:   editors,
+		http.MethodPut:    editors,
+		http.MethodPatch:  editors,
+		http.MethodDelete: editors,
+	}
+	scriptRequestPolicy := oAuth.Policy{
+		http.MethodGet:    oAuth.AllRoles,
+		http.MethodPost:   {oAuth.RoleAdmin, oAuth.RoleAuthor, oAuth.RoleReviewer},
+		http.MethodPut:    {oAuth.RoleAdmin, oAuth.RoleAuthor, oAuth.RoleReviewer},
+		http.MethodPatch:  {oAuth.RoleAdmin, oAuth.RoleAuthor, oAuth.RoleReviewer},
+		http.MethodDelete: editors,
+	}
+	// Anyone who may file a request may upload its attachments
+	artifactPolicy := oAuth.Policy{
+		http.MethodGet:    oAuth.AllRoles,
+		http.MethodPost:   scriptRequestPolicy[http.MethodPost],
+		http.MethodDelete: editors,
+	}
+	restorePolicy := oAuth.Policy{oAuth.AnyMethod: editors}
+	trashPolicy := oAuth.Policy{
+		http.MethodGet:  editors,
+		oAuth.AnyMethod: {oAuth.RoleAdmin},
+	}
+	adminOnly := oAuth.Policy{oAuth.AnyMethod: {oAuth.RoleAdmin}}
+	actorPolicy := oAuth.ReadOnly(oAuth.RoleAdmin, oAuth.RoleSPCoordinator)
+
 	// ── Health check (public) ──────────────────────────────────────────────────
 	mux.HandleFunc("/api/health", func(w http.ResponseWriter, r *http.Request) {
 		status := "ok"
@@ -144,48 +232,55 @@ func main() {
 	})
 
 	// ── Actor routes ──────────────────────────────────────────────────────────
-	// Actor routes are public — wrap with authMiddleware below if you want them protected.
-	mux.Handle("/api/actors/", api.ActorsHandler(actorDB))
-	mux.Handle("/api/actors", api.ActorsHandler(actorDB))
+	// Every role may look actors up for casting; contact and payroll fields are
+	// filtered per role inside the handler.
+	mux.Handle("/api/actors/", protect(api.ActorsHandler(actorDB), actorPolicy))
+	mux.Handle("/api/actors", protect(api.ActorsHandler(actorDB), actorPolicy))
 
 	// ── Mongo-backed API routes ───────────────────────────────────────────────
 	if authMiddleware != nil {
-		log.Println("Applying authentication to API endpoints")
-		mux.Handle("/api/script-request", authMiddleware.Middleware(api.ScriptRequestHandler(mongoClient)))
-		mux.Handle("/api/document/versions", authMiddleware.Middleware(api.DocumentHandler(mongoClient)))
-		mux.Handle("/api/document/version", authMiddleware.Middleware(api.DocumentHandler(mongoClient)))
-		mux.Handle("/api/document/restore", authMiddleware.Middleware(api.DocumentHandler(mongoClient)))
-		mux.Handle("/api/document/medications", authMiddleware.Middleware(api.DocumentHandler(mongoClient)))
-		mux.Handle("/api/document/vitals", authMiddleware.Middleware(api.DocumentHandler(mongoClient)))
-		mux.Handle("/api/document", authMiddleware.Middleware(api.DocumentHandler(mongoClient)))
-		mux.Handle("/api/artifact", authMiddleware.Middleware(api.ArtifactHandler(mongoClient)))
-		mux.Handle("/api/artifact/", authMiddleware.Middleware(api.ArtifactHandler(mongoClient)))
-		mux.Handle("/api/artifacts", authMiddleware.Middleware(api.ArtifactHandler(mongoClient)))
-		mux.Handle("/api/artifacts/", authMiddleware.Middleware(api.ArtifactHandler(mongoClient)))
+		log.Println("Applying authentication and role policies to API endpoints")
+	} else {
+		log.Println("API endpoints are PUBLIC (no authentication)")
+	}
+	mux.Handle("/api/script-request", protect(api.ScriptRequestHandler(mongoClient), scriptRequestPolicy))
+	mux.Handle("/api/script-request/undelete", protect(api.ScriptRequestHandler(mongoClient), restorePolicy))
+	mux.Handle("/api/script-request/", protect(api.ScriptRequestHandler(mongoClient), scriptRequestPolicy))
+	mux.Handle("/api/document/versions", protect(api.DocumentHandler(mongoClient), readOnly))
+	mux.Handle("/api/document/version", protect(api.DocumentHandler(mongoClient), readOnly))
+	mux.Handle("/api/document/diff", protect(api.DocumentHandler(mongoClient), readOnly))
+	mux.Handle("/api/document/restore", protect(api.DocumentHandler(mongoClient), restorePolicy))
+	mux.Handle("/api/document/undelete", protect(api.DocumentHandler(mongoClient), restorePolicy))
+	mux.Handle("/api/document/facets", protect(api.DocumentHandler(mongoClient), readOnly))
+	mux.Handle("/api/document/medications", protect(api.DocumentHandler(mongoClient), readOnly))
+	mux.Handle("/api/document/vitals", protect(api.DocumentHandler(mongoClient), readOnly))
+	mux.Handle("/api/document", protect(api.DocumentHandler(mongoClient), documentPolicy))
+	mux.Handle("/api/trash", protect(api.TrashHandler(mongoClient, artifactStore, trashRetention), trashPolicy))
+	mux.Handle("/api/integrity", protect(api.IntegrityHandler(mongoClient, artifactStore), adminOnly))
+	mux.Handle("/api/artifact/thumbnail", protect(api.ArtifactThumbnailHandler(artifactStore), readOnly))
+	mux.Handle("/api/artifact/orphans", protect(api.ArtifactOrphansHandler(mongoClient, artifactStore, artifactGrace), adminOnly))
+	mux.Handle("/api/document/artifacts", protect(api.DocumentArtifactsHandler(mongoClient, artifactStore), documentPolicy))
+	mux.Handle("/api/artifact", protect(api.ArtifactHandler(mongoClient, artifactStore), artifactPolicy))
+	mux.Handle("/api/artifact/", protect(api.ArtifactHandler(mongoClient, artifactStore), artifactPolicy))
+	mux.Handle("/api/artifacts", protect(api.ArtifactHandler(mongoClient, artifactStore), artifactPolicy))
+	mux.Handle("/api/artifacts/", protect(api.ArtifactHandler(mongoClient, artifactStore), artifactPolicy))
 
-		// FIX: was missing closing paren on w.Write([]byte(...))
-		mux.HandleFunc("/api/user", func(w http.ResponseWriter, r *http.Request) {
+	if authMiddleware != nil {
+		mux.Handle("/api/user", protect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			claims, ok := oAuth.GetClaimsFromContext(r.Context())
 			if !ok {
 				http.Error(w, "Failed to get user claims", http.StatusInternalServerError)
 				return
 			}
+			roles, _ := oAuth.GetRolesFromContext(r.Context())
 			w.Header().Set("Content-Type", "application/json")
-			fmt.Fprintf(w, `{"user_id":%q,"email":%q,"name":%q}`, claims.Subject, claims.Email, claims.Name)
-		})
-	} else {
-		log.Println("API endpoints are PUBLIC (no authentication)")
-		mux.Handle("/api/script-request", api.ScriptRequestHandler(mongoClient))
-		mux.Handle("/api/document/versions", api.DocumentHandler(mongoClient))
-		mux.Handle("/api/document/version", api.DocumentHandler(mongoClient))
-		mux.Handle("/api/document/restore", api.DocumentHandler(mongoClient))
-		mux.Handle("/api/document/medications", api.DocumentHandler(mongoClient))
-		mux.Handle("/api/document/vitals", api.DocumentHandler(mongoClient))
-		mux.Handle("/api/document", api.DocumentHandler(mongoClient))
-		mux.Handle("/api/artifact", api.ArtifactHandler(mongoClient))
-		mux.Handle("/api/artifact/", api.ArtifactHandler(mongoClient))
-		mux.Handle("/api/artifacts", api.ArtifactHandler(mongoClient))
-		mux.Handle("/api/artifacts/", api.ArtifactHandler(mongoClient))
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"user_id": claims.Subject,
+				"email":   claims.Email,
+				"name":    claims.Name,
+				"roles":   roles,
+			})
+		}), oAuth.Policy{}))
 	}
 
 	// ── SPA (must be last) ────────────────────────────────────────────────────
@@ -0,0 +1,276 @@
+package artifactstore
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// BucketName is the GridFS bucket artifacts have always been stored in
+const BucketName = "artifacts"
+
+// variantBucketName holds the derived files, named <artifact id>/<variant>
+const variantBucketName = "artifact_variants"
+
+// GridFS keeps artifacts in MongoDB
+type GridFS struct {
+	database *mongo.Database
+}
+
+// NewGridFS returns a store on the artifacts bucket of database
+func NewGridFS(database *mongo.Database) *GridFS {
+	return &GridFS{database: database}
+}
+
+func (s *GridFS) Kind() string { return "gridfs" }
+
+// bucket opens the named bucket with ctx's deadline. Buckets are cheap and their
+// deadlines are not safe to share, so every call gets its own.
+func (s *GridFS) bucket(ctx context.Context, name string) (*gridfs.Bucket, error) {
+	bucket, err := gridfs.NewBucket(s.database, options.GridFSBucket().SetName(name))
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := bucket.SetReadDeadline(deadline); err != nil {
+			return nil, err
+		}
+		if err := bucket.SetWriteDeadline(deadline); err != nil {
+			return nil, err
+		}
+	}
+	return bucket, nil
+}
+
+func (s *GridFS) Put(ctx context.Context, info Info, r io.Reader) (Info, error) {
+	info, err := prepare(info)
+	if err != nil {
+		return info, err
+	}
+	objectID, _ := primitive.ObjectIDFromHex(info.ID)
+
+	// The digest goes into the metadata, which is written before the content
+	body, digest, err := readAll(r)
+	if err != nil {
+		return info, err
+	}
+
+	bucket, err := s.bucket(ctx, BucketName)
+	if err != nil {
+		return info, err
+	}
+	opts := options.GridFSUpload().SetMetadata(bson.M{
+		"content_type": info.ContentType,
+		"size":         int64(len(body)),
+		"uploaded_at":  info.UploadedAt.Format(time.RFC3339),
+		"uploaded_by":  info.UploadedBy,
+		"sha256":       digest,
+	})
+	if err := bucket.UploadFromStreamWithID(objectID, info.Name, bytes.NewReader(body), opts); err != nil {
+		return info, err
+	}
+	return s.Stat(ctx, info.ID)
+}
+
+func (s *GridFS) Open(ctx context.Context, id string) (io.ReadCloser, Info, error) {
+	info, err := s.Stat(ctx, id)
+	if err != nil {
+		return nil, info, err
+	}
+	objectID, _ := primitive.ObjectIDFromHex(id)
+
+	bucket, err := s.bucket(ctx, BucketName)
+	if err != nil {
+		return nil, info, err
+	}
+	stream, err := bucket.OpenDownloadStream(objectID)
+	if err == gridfs.ErrFileNotFound {
+		return nil, info, ErrNotFound
+	}
+	if err != nil {
+		return nil, info, err
+	}
+	return stream, info, nil
+}
+
+func (s *GridFS) Stat(ctx context.Context, id string) (Info, error) {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return Info{}, ErrNotFound
+	}
+	var file gridFSFile
+	err = s.database.Collection(BucketName+".files").FindOne(ctx, bson.M{"_id": objectID}).Decode(&file)
+	if err == mongo.ErrNoDocuments {
+		return Info{}, ErrNotFound
+	}
+	if err != nil {
+		return Info{}, err
+	}
+	return file.info(), nil
+}
+
+func (s *GridFS) Delete(ctx context.Context, id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrNotFound
+	}
+	bucket, err := s.bucket(ctx, BucketName)
+	if err != nil {
+		return err
+	}
+	err = bucket.DeleteContext(ctx, objectID)
+	if err == gridfs.ErrFileNotFound {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return s.deleteVariants(ctx, bson.M{"metadata.artifact_id": strings.ToLower(id)})
+}
+
+func (s *GridFS) PutVariant(ctx context.Context, id, variant, contentType string, r io.Reader) error {
+	if !primitive.IsValidObjectID(id) || !validVariant(variant) {
+		return fmt.Errorf("invalid artifact variant %q of %q", variant, id)
+	}
+	id = strings.ToLower(id)
+	bucket, err := s.bucket(ctx, variantBucketName)
+	if err != nil {
+		return err
+	}
+
+	// Upload the new file before dropping the old ones so readers always find one
+	newID := primitive.NewObjectID()
+	opts := options.GridFSUpload().SetMetadata(bson.M{
+		"artifact_id":  id,
+		"variant":      variant,
+		"content_type": contentType,
+	})
+	if err := bucket.UploadFromStreamWithID(newID, id+"/"+variant, r, opts); err != nil {
+		return err
+	}
+	return s.deleteVariants(ctx, bson.M{"filename": id + "/" + variant, "_id": bson.M{"$ne": newID}})
+}
+
+func (s *GridFS) OpenVariant(ctx context.Context, id, variant string) (io.ReadCloser, Info, error) {
+	if !primitive.IsValidObjectID(id) || !validVariant(variant) {
+		return nil, Info{}, ErrNotFound
+	}
+	var file gridFSFile
+	opts := options.FindOne().SetSort(bson.D{{Key: "uploadDate", Value: -1}})
+	err := s.database.Collection(variantBucketName+".files").
+		FindOne(ctx, bson.M{"filename": strings.ToLower(id) + "/" + variant}, opts).Decode(&file)
+	if err == mongo.ErrNoDocuments {
+		return nil, Info{}, ErrNotFound
+	}
+	if err != nil {
+		return nil, Info{}, err
+	}
+
+	bucket, err := s.bucket(ctx, variantBucketName)
+	if err != nil {
+		return nil, Info{}, err
+	}
+	stream, err := bucket.OpenDownloadStream(file.ID)
+	if err == gridfs.ErrFileNotFound {
+		return nil, Info{}, ErrNotFound // replaced meanwhile
+	}
+	if err != nil {
+		return nil, Info{}, err
+	}
+	return stream, file.info(), nil
+}
+
+// deleteVariants removes the variant files matching filter
+func (s *GridFS) deleteVariants(ctx context.Context, filter bson.M) error {
+	cursor, err := s.database.Collection(variantBucketName+".files").Find(ctx, filter, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return err
+	}
+	var files []gridFSFile
+	if err := cursor.All(ctx, &files); err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return nil
+	}
+
+	bucket, err := s.bucket(ctx, variantBucketName)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if err := bucket.DeleteContext(ctx, file.ID); err != nil && err != gridfs.ErrFileNotFound {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *GridFS) Walk(ctx context.Context, fn func(Info) error) error {
+	opts := options.Find().SetSort(bson.D{{Key: "uploadDate", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := s.database.Collection(BucketName+".files").Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var file gridFSFile
+		if err := cursor.Decode(&file); err != nil {
+			return fmt.Errorf("decoding artifact %v: %w", cursor.Current.Lookup("_id"), err)
+		}
+		if err := fn(file.info()); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+// gridFSFile is a document of the files collection
+type gridFSFile struct {
+	ID         primitive.ObjectID `bson:"_id"`
+	Filename   string             `bson:"filename"`
+	Length     int64              `bson:"length"`
+	UploadDate time.Time          `bson:"uploadDate"`
+	MD5        string             `bson:"md5,omitempty"` // only written by older drivers
+	Metadata   struct {
+		ContentType       string `bson:"content_type"`
+		LegacyContentType string `bson:"contentType"`
+		UploadedAt        string `bson:"uploaded_at"`
+		UploadedBy        string `bson:"uploaded_by"`
+		SHA256            string `bson:"sha256"`
+	} `bson:"metadata"`
+}
+
+// info describes the file. The driver sets uploadDate to the time of the upload
+// itself, so the upload time recorded in the metadata wins; it survives a migration
+// from another backend.
+func (f gridFSFile) info() Info {
+	contentType := f.Metadata.ContentType
+	if contentType == "" {
+		contentType = f.Metadata.LegacyContentType
+	}
+	uploadedAt := f.UploadDate
+	if at, err := time.Parse(time.RFC3339, f.Metadata.UploadedAt); err == nil {
+		uploadedAt = at
+	}
+	return Info{
+		ID:          f.ID.Hex(),
+		Name:        f.Filename,
+		ContentType: contentType,
+		Size:        f.Length,
+		UploadedAt:  uploadedAt.UTC(),
+		UploadedBy:  f.Metadata.UploadedBy,
+		SHA256:      f.Metadata.SHA256,
+		MD5:         f.MD5,
+	}
+}
@@ -0,0 +1,281 @@
+package artifactstore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Local keeps artifacts on disk, each as <id> with its Info in <id>.json, and their
+// variants as <id>.<variant> with <id>.<variant>.json
+type Local struct {
+	dir string
+}
+
+// localInfo is the sidecar file written next to each artifact
+type localInfo struct {
+	Name        string    `json:"name"`
+	ContentType string    `json:"content_type"`
+	UploadedAt  time.Time `json:"uploaded_at"`
+	UploadedBy  string    `json:"uploaded_by,omitempty"`
+	SHA256      string    `json:"sha256,omitempty"`
+}
+
+// NewLocal returns a store in dir, creating it if needed
+func NewLocal(dir string) (*Local, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+	return &Local{dir: dir}, nil
+}
+
+func (s *Local) Kind() string { return "local" }
+
+// path returns the content file of id. IDs are checked first so a request can never
+// name a file outside dir.
+func (s *Local) path(id string) (string, bool) {
+	if !primitive.IsValidObjectID(id) {
+		return "", false
+	}
+	return filepath.Join(s.dir, strings.ToLower(id)), true
+}
+
+func (s *Local) Put(ctx context.Context, info Info, r io.Reader) (Info, error) {
+	info, err := prepare(info)
+	if err != nil {
+		return info, err
+	}
+	path, _ := s.path(info.ID)
+
+	// Write both files under temporary names so readers never see a partial artifact
+	tmp, err := os.CreateTemp(s.dir, ".upload-*")
+	if err != nil {
+		return info, err
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return info, err
+	}
+	info.Size = size
+	info.SHA256 = hex.EncodeToString(hash.Sum(nil))
+
+	meta, err := json.Marshal(localInfo{
+		Name:        info.Name,
+		ContentType: info.ContentType,
+		UploadedAt:  info.UploadedAt,
+		UploadedBy:  info.UploadedBy,
+		SHA256:      info.SHA256,
+	})
+	if err != nil {
+		return info, err
+	}
+	if err := os.WriteFile(tmp.Name()+".json", meta, 0o640); err != nil {
+		return info, err
+	}
+	defer os.Remove(tmp.Name() + ".json")
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return info, err
+	}
+	if err := os.Rename(tmp.Name()+".json", path+".json"); err != nil {
+		return info, err
+	}
+	return info, nil
+}
+
+func (s *Local) Open(ctx context.Context, id string) (io.ReadCloser, Info, error) {
+	info, err := s.Stat(ctx, id)
+	if err != nil {
+		return nil, info, err
+	}
+	path, _ := s.path(id)
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, info, ErrNotFound
+	}
+	if err != nil {
+		return nil, info, err
+	}
+	return file, info, nil
+}
+
+func (s *Local) Stat(ctx context.Context, id string) (Info, error) {
+	path, ok := s.path(id)
+	if !ok {
+		return Info{}, ErrNotFound
+	}
+	stat, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Info{}, ErrNotFound
+	}
+	if err != nil {
+		return Info{}, err
+	}
+
+	info := Info{ID: strings.ToLower(id), Size: stat.Size(), UploadedAt: stat.ModTime().UTC()}
+	raw, err := os.ReadFile(path + ".json")
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return info, err
+	}
+	if err == nil {
+		var meta localInfo
+		if err := json.Unmarshal(raw, &meta); err != nil {
+			return info, err
+		}
+		info.Name = meta.Name
+		info.ContentType = meta.ContentType
+		info.UploadedBy = meta.UploadedBy
+		info.SHA256 = meta.SHA256
+		if !meta.UploadedAt.IsZero() {
+			info.UploadedAt = meta.UploadedAt.UTC()
+		}
+	}
+	return info, nil
+}
+
+func (s *Local) Delete(ctx context.Context, id string) error {
+	path, ok := s.path(id)
+	if !ok {
+		return ErrNotFound
+	}
+	err := os.Remove(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path + ".json"); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	// IDs are hex, so the pattern cannot match another artifact's files
+	variants, err := filepath.Glob(path + ".*")
+	if err != nil {
+		return err
+	}
+	for _, variant := range variants {
+		if err := os.Remove(variant); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Local) PutVariant(ctx context.Context, id, variant, contentType string, r io.Reader) error {
+	path, ok := s.path(id)
+	if !ok || !validVariant(variant) {
+		return fmt.Errorf("invalid artifact variant %q of %q", variant, id)
+	}
+	path += "." + variant
+
+	tmp, err := os.CreateTemp(s.dir, ".variant-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	meta, err := json.Marshal(localInfo{ContentType: contentType, UploadedAt: time.Now().UTC()})
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path+".json", meta, 0o640); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func (s *Local) OpenVariant(ctx context.Context, id, variant string) (io.ReadCloser, Info, error) {
+	path, ok := s.path(id)
+	if !ok || !validVariant(variant) {
+		return nil, Info{}, ErrNotFound
+	}
+	path += "." + variant
+
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, Info{}, ErrNotFound
+	}
+	if err != nil {
+		return nil, Info{}, err
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, Info{}, err
+	}
+
+	info := Info{ID: strings.ToLower(id), Size: stat.Size(), UploadedAt: stat.ModTime().UTC()}
+	if raw, err := os.ReadFile(path + ".json"); err == nil {
+		var meta localInfo
+		if json.Unmarshal(raw, &meta) == nil {
+			info.ContentType = meta.ContentType
+		}
+	}
+	return file, info, nil
+}
+
+func (s *Local) Walk(ctx context.Context, fn func(Info) error) error {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+
+	var infos []Info
+	for _, entry := range entries {
+		if entry.IsDir() || !primitive.IsValidObjectID(entry.Name()) {
+			continue
+		}
+		info, err := s.Stat(ctx, entry.Name())
+		if err == ErrNotFound {
+			continue // deleted meanwhile
+		}
+		if err != nil {
+			return err
+		}
+		infos = append(infos, info)
+	}
+
+	// Same order as the GridFS store
+	sort.SliceStable(infos, func(i, j int) bool {
+		if !infos[i].UploadedAt.Equal(infos[j].UploadedAt) {
+			return infos[i].UploadedAt.Before(infos[j].UploadedAt)
+		}
+		return infos[i].ID < infos[j].ID
+	})
+	for _, info := range infos {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,451 @@
+package artifactstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// S3Config locates an S3-compatible bucket. Any service speaking the S3 REST API with
+// Signature Version 4 works, including MinIO for local development.
+type S3Config struct {
+	Endpoint    string // e.g. https://s3.us-east-1.amazonaws.com or http://localhost:9000
+	Bucket      string
+	Region      string // defaults to us-east-1
+	AccessKey   string
+	SecretKey   string
+	Prefix      string // prepended to every object key
+	VirtualHost bool   // address the bucket as bucket.endpoint instead of endpoint/bucket
+}
+
+// S3 keeps artifacts as objects in a bucket, with the file name and upload time in
+// user metadata
+type S3 struct {
+	config   S3Config
+	endpoint *url.URL
+	client   *http.Client
+}
+
+// Header names of the metadata stored with each object
+const (
+	s3NameHeader       = "X-Amz-Meta-Name"
+	s3UploadedAtHeader = "X-Amz-Meta-Uploaded-At"
+	s3UploadedByHeader = "X-Amz-Meta-Uploaded-By"
+	s3SHA256Header     = "X-Amz-Meta-Sha256"
+)
+
+// emptyPayloadHash is the SHA-256 of an empty body
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// NewS3 returns a store on the configured bucket
+func NewS3(config S3Config) (*S3, error) {
+	if config.Endpoint == "" || config.Bucket == "" {
+		return nil, errors.New("s3 artifact store needs ARTIFACT_S3_ENDPOINT and ARTIFACT_S3_BUCKET")
+	}
+	if config.AccessKey == "" || config.SecretKey == "" {
+		return nil, errors.New("s3 artifact store needs ARTIFACT_S3_ACCESS_KEY and ARTIFACT_S3_SECRET_KEY")
+	}
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+	endpoint, err := url.Parse(strings.TrimSuffix(config.Endpoint, "/"))
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid ARTIFACT_S3_ENDPOINT %q", config.Endpoint)
+	}
+	return &S3{config: config, endpoint: endpoint, client: &http.Client{Timeout: time.Minute}}, nil
+}
+
+func (s *S3) Kind() string { return "s3" }
+
+// objectURL returns the URL of the object for id, or of the bucket when key is empty
+func (s *S3) objectURL(key string, query url.Values) *url.URL {
+	u := *s.endpoint
+	path := strings.TrimSuffix(u.Path, "/")
+	if s.config.VirtualHost {
+		u.Host = s.config.Bucket + "." + u.Host
+	} else {
+		path += "/" + url.PathEscape(s.config.Bucket)
+	}
+	if key != "" {
+		segments := strings.Split(key, "/")
+		for i, segment := range segments {
+			segments[i] = url.PathEscape(segment)
+		}
+		path += "/" + strings.Join(segments, "/")
+	} else if s.config.VirtualHost {
+		path += "/"
+	}
+	u.RawPath = path
+	u.Path, _ = url.PathUnescape(path)
+	if query != nil {
+		u.RawQuery = canonicalQuery(query)
+	}
+	return &u
+}
+
+// variantKey is the key of a variant, next to its artifact's key. Walk skips it
+// because it is not a bare ID.
+func (s *S3) variantKey(id, variant string) string {
+	return s.key(id) + "." + variant
+}
+
+func (s *S3) key(id string) string {
+	return s.config.Prefix + strings.ToLower(id)
+}
+
+func (s *S3) Put(ctx context.Context, info Info, r io.Reader) (Info, error) {
+	info, err := prepare(info)
+	if err != nil {
+		return info, err
+	}
+
+	// Artifacts are small, so the body is buffered to sign its hash
+	body, digest, err := readAll(r)
+	if err != nil {
+		return info, err
+	}
+	info.Size = int64(len(body))
+	info.SHA256 = digest
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.objectURL(s.key(info.ID), nil).String(), bytes.NewReader(body))
+	if err != nil {
+		return info, err
+	}
+	req.ContentLength = info.Size
+	if info.ContentType != "" {
+		req.Header.Set("Content-Type", info.ContentType)
+	}
+	req.Header.Set(s3NameHeader, url.QueryEscape(info.Name)) // header values must be ASCII
+	req.Header.Set(s3UploadedAtHeader, info.UploadedAt.Format(time.RFC3339))
+	if info.UploadedBy != "" {
+		req.Header.Set(s3UploadedByHeader, url.QueryEscape(info.UploadedBy))
+	}
+
+	req.Header.Set(s3SHA256Header, digest)
+
+	resp, err := s.do(req, digest)
+	if err != nil {
+		return info, err
+	}
+	resp.Body.Close()
+	return info, nil
+}
+
+func (s *S3) Open(ctx context.Context, id string) (io.ReadCloser, Info, error) {
+	if !primitive.IsValidObjectID(id) {
+		return nil, Info{}, ErrNotFound
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.objectURL(s.key(id), nil).String(), nil)
+	if err != nil {
+		return nil, Info{}, err
+	}
+	resp, err := s.do(req, emptyPayloadHash)
+	if err != nil {
+		return nil, Info{}, err
+	}
+	return resp.Body, s.info(id, resp), nil
+}
+
+func (s *S3) Stat(ctx context.Context, id string) (Info, error) {
+	if !primitive.IsValidObjectID(id) {
+		return Info{}, ErrNotFound
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, s.objectURL(s.key(id), nil).String(), nil)
+	if err != nil {
+		return Info{}, err
+	}
+	resp, err := s.do(req, emptyPayloadHash)
+	if err != nil {
+		return Info{}, err
+	}
+	resp.Body.Close()
+	return s.info(id, resp), nil
+}
+
+// Delete removes the object. S3 reports success for missing keys, so the object is
+// looked up first to keep ErrNotFound meaningful.
+func (s *S3) Delete(ctx context.Context, id string) error {
+	if _, err := s.Stat(ctx, id); err != nil {
+		return err
+	}
+	if err := s.deleteKey(ctx, s.key(id)); err != nil {
+		return err
+	}
+
+	// Variants share the artifact's key as prefix
+	keys, err := s.list(ctx, s.key(id)+".")
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := s.deleteKey(ctx, key); err != nil && err != ErrNotFound {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *S3) PutVariant(ctx context.Context, id, variant, contentType string, r io.Reader) error {
+	if !primitive.IsValidObjectID(id) || !validVariant(variant) {
+		return fmt.Errorf("invalid artifact variant %q of %q", variant, id)
+	}
+	body, digest, err := readAll(r)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.objectURL(s.variantKey(id, variant), nil).String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = int64(len(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := s.do(req, digest)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (s *S3) OpenVariant(ctx context.Context, id, variant string) (io.ReadCloser, Info, error) {
+	if !primitive.IsValidObjectID(id) || !validVariant(variant) {
+		return nil, Info{}, ErrNotFound
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.objectURL(s.variantKey(id, variant), nil).String(), nil)
+	if err != nil {
+		return nil, Info{}, err
+	}
+	resp, err := s.do(req, emptyPayloadHash)
+	if err != nil {
+		return nil, Info{}, err
+	}
+	return resp.Body, s.info(id, resp), nil
+}
+
+func (s *S3) deleteKey(ctx context.Context, key string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, s.objectURL(key, nil).String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(req, emptyPayloadHash)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// listBucketResult is the part of a ListObjectsV2 response the store reads
+type listBucketResult struct {
+	Contents []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// Walk lists the objects under the prefix. Listings carry no user metadata, so each
+// artifact is looked up with HEAD before fn sees it.
+func (s *S3) Walk(ctx context.Context, fn func(Info) error) error {
+	keys, err := s.list(ctx, s.config.Prefix)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		id := strings.TrimPrefix(key, s.config.Prefix)
+		if !primitive.IsValidObjectID(id) {
+			continue // a variant, or not written by this store
+		}
+		info, err := s.Stat(ctx, id)
+		if err == ErrNotFound {
+			continue // deleted meanwhile
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// list returns the keys starting with prefix, following continuation tokens
+func (s *S3) list(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}}
+		if prefix != "" {
+			query.Set("prefix", prefix)
+		}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.objectURL("", query).String(), nil)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := s.do(req, emptyPayloadHash)
+		if err != nil {
+			return nil, err
+		}
+		var page listBucketResult
+		err = xml.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("decoding bucket listing: %w", err)
+		}
+
+		for _, object := range page.Contents {
+			keys = append(keys, object.Key)
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return keys, nil
+		}
+		token = page.NextContinuationToken
+	}
+}
+
+// info reads the artifact description from object response headers
+func (s *S3) info(id string, resp *http.Response) Info {
+	info := Info{
+		ID:          strings.ToLower(id),
+		ContentType: resp.Header.Get("Content-Type"),
+		Size:        resp.ContentLength,
+	}
+	if name, err := url.QueryUnescape(resp.Header.Get(s3NameHeader)); err == nil {
+		info.Name = name
+	}
+	if by, err := url.QueryUnescape(resp.Header.Get(s3UploadedByHeader)); err == nil {
+		info.UploadedBy = by
+	}
+	info.SHA256 = resp.Header.Get(s3SHA256Header)
+	if at, err := time.Parse(time.RFC3339, resp.Header.Get(s3UploadedAtHeader)); err == nil {
+		info.UploadedAt = at.UTC()
+	} else if at, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		info.UploadedAt = at.UTC()
+	}
+	if info.Size < 0 {
+		info.Size, _ = strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64)
+	}
+	return info
+}
+
+// do signs and sends req, turning 404 into ErrNotFound and other failures into errors
+// that carry the service's message
+func (s *S3) do(req *http.Request, payloadHash string) (*http.Response, error) {
+	s.sign(req, payloadHash, time.Now().UTC())
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("s3 %s %s: %s %s", req.Method, req.URL.Path, resp.Status, strings.TrimSpace(string(message)))
+	}
+	return resp, nil
+}
+
+// sign adds an AWS Signature Version 4 Authorization header. Only host and the
+// x-amz-* headers are signed, which every S3 implementation accepts.
+func (s *S3) sign(req *http.Request, payloadHash string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		if strings.HasPrefix(lower, "x-amz-") {
+			headers[lower] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := day + "/" + s.config.Region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.config.SecretKey), day)
+	key = hmacSHA256(key, s.config.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+s.config.AccessKey+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// canonicalQuery encodes a query string the way SigV4 expects: keys sorted and
+// spaces as %20 rather than +
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, key := range keys {
+		values := append([]string(nil), query[key]...)
+		sort.Strings(values)
+		for _, value := range values {
+			parts = append(parts, sigv4Escape(key)+"="+sigv4Escape(value))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+func sigv4Escape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
@@ -0,0 +1,148 @@
+// Package artifactstore keeps the files uploaded as script and request artifacts.
+// The API only talks to the Store interface; which backend holds the bytes is a
+// deployment choice made with ARTIFACT_STORE.
+package artifactstore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ErrNotFound is returned when no artifact has the requested ID
+var ErrNotFound = errors.New("artifact not found")
+
+// ErrNoDatabase is returned by FromEnv when the GridFS backend is selected but there
+// is no MongoDB connection
+var ErrNoDatabase = errors.New("gridfs artifact store needs a MongoDB connection")
+
+// Info describes a stored artifact
+type Info struct {
+	ID          string
+	Name        string
+	ContentType string
+	Size        int64
+	UploadedAt  time.Time
+	UploadedBy  string
+
+	// SHA256 is the hex digest of the content, recorded by Put. MD5 is only known for
+	// GridFS files written by older drivers. Either may be empty for old artifacts.
+	SHA256 string
+	MD5    string
+}
+
+// Store is an artifact backend. IDs are ObjectID hex strings in every backend, so
+// artifacts keep their ID (and the links to them) when they move between backends.
+type Store interface {
+	// Kind names the backend: gridfs, local or s3
+	Kind() string
+
+	// Put stores the content read from r. An empty info.ID gets a new ID and a zero
+	// UploadedAt the current time; the stored Info is returned.
+	Put(ctx context.Context, info Info, r io.Reader) (Info, error)
+
+	// Open returns the content of an artifact; the caller closes it
+	Open(ctx context.Context, id string) (io.ReadCloser, Info, error)
+
+	Stat(ctx context.Context, id string) (Info, error)
+	Delete(ctx context.Context, id string) error
+
+	// Walk calls fn for every stored artifact, stopping at the first error
+	Walk(ctx context.Context, fn func(Info) error) error
+
+	// PutVariant stores a file derived from artifact id, such as a thumbnail, under a
+	// variant name, replacing an earlier one. Variants are not artifacts: Walk skips
+	// them and Delete removes them with their artifact.
+	PutVariant(ctx context.Context, id, variant, contentType string, r io.Reader) error
+
+	// OpenVariant returns a derived file; ErrNotFound if it was never stored
+	OpenVariant(ctx context.Context, id, variant string) (io.ReadCloser, Info, error)
+}
+
+// FromEnv opens the store named by ARTIFACT_STORE, GridFS when it is unset
+func FromEnv(client *mongo.Client) (Store, error) {
+	return ByKind(os.Getenv("ARTIFACT_STORE"), client)
+}
+
+// ByKind opens the gridfs, local or s3 store, configured from the environment:
+//
+//	gridfs - the "artifacts" bucket of the vccwebsite database
+//	local  - ARTIFACT_DIR (default ./artifacts)
+//	s3     - ARTIFACT_S3_ENDPOINT, ARTIFACT_S3_BUCKET, ARTIFACT_S3_ACCESS_KEY,
+//	         ARTIFACT_S3_SECRET_KEY, and optionally ARTIFACT_S3_REGION (us-east-1),
+//	         ARTIFACT_S3_PREFIX and ARTIFACT_S3_VIRTUAL_HOST=true for
+//	         bucket.endpoint addressing instead of endpoint/bucket
+func ByKind(kind string, client *mongo.Client) (Store, error) {
+	switch strings.ToLower(strings.TrimSpace(kind)) {
+	case "", "gridfs":
+		if client == nil {
+			return nil, ErrNoDatabase
+		}
+		return NewGridFS(client.Database("vccwebsite")), nil
+	case "local":
+		dir := os.Getenv("ARTIFACT_DIR")
+		if dir == "" {
+			dir = "./artifacts"
+		}
+		return NewLocal(dir)
+	case "s3":
+		return NewS3(S3Config{
+			Endpoint:    os.Getenv("ARTIFACT_S3_ENDPOINT"),
+			Bucket:      os.Getenv("ARTIFACT_S3_BUCKET"),
+			Region:      os.Getenv("ARTIFACT_S3_REGION"),
+			AccessKey:   os.Getenv("ARTIFACT_S3_ACCESS_KEY"),
+			SecretKey:   os.Getenv("ARTIFACT_S3_SECRET_KEY"),
+			Prefix:      os.Getenv("ARTIFACT_S3_PREFIX"),
+			VirtualHost: os.Getenv("ARTIFACT_S3_VIRTUAL_HOST") == "true",
+		})
+	default:
+		return nil, fmt.Errorf("unknown artifact store %q; use gridfs, local or s3", kind)
+	}
+}
+
+// prepare fills in the ID and upload time of a new artifact and checks a given ID
+func prepare(info Info) (Info, error) {
+	if info.ID == "" {
+		info.ID = primitive.NewObjectID().Hex()
+	} else if !primitive.IsValidObjectID(info.ID) {
+		return info, fmt.Errorf("invalid artifact ID %q", info.ID)
+	}
+	if info.UploadedAt.IsZero() {
+		info.UploadedAt = time.Now()
+	}
+	info.UploadedAt = info.UploadedAt.UTC()
+	return info, nil
+}
+
+// validVariant reports whether a variant name is safe to use in file names and keys
+func validVariant(variant string) bool {
+	if variant == "" {
+		return false
+	}
+	for _, c := range variant {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// readAll buffers an upload and returns it with its SHA-256 hex digest.
+// Artifacts are capped at a few MB by the API, so holding one in memory is fine.
+func readAll(r io.Reader) ([]byte, string, error) {
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return nil, "", err
+	}
+	sum := sha256.Sum256(body)
+	return body, hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,125 @@
+// Package jsondiff computes field-level differences between JSON documents.
+package jsondiff
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Operation kinds reported in a Change
+const (
+	Added   = "added"
+	Removed = "removed"
+	Changed = "changed"
+)
+
+// Change is a single difference between two documents
+type Change struct {
+	Path string      `json:"path"` // e.g. med_hist.medications[2].dose
+	Op   string      `json:"op"`
+	From interface{} `json:"from,omitempty"`
+	To   interface{} `json:"to,omitempty"`
+}
+
+// Diff compares the JSON encodings of a and b and returns the changes needed to turn
+// a into b, ordered by path. Arrays are compared position by position.
+func Diff(a, b interface{}) ([]Change, error) {
+	left, err := normalize(a)
+	if err != nil {
+		return nil, err
+	}
+	right, err := normalize(b)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := []Change{}
+	walk("", left, right, &changes)
+	return changes, nil
+}
+
+// normalize converts v into the generic map/slice form produced by encoding/json
+func normalize(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out interface{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func walk(path string, a, b interface{}, changes *[]Change) {
+	switch left := a.(type) {
+	case map[string]interface{}:
+		if right, ok := b.(map[string]interface{}); ok {
+			walkObject(path, left, right, changes)
+			return
+		}
+	case []interface{}:
+		if right, ok := b.([]interface{}); ok {
+			walkArray(path, left, right, changes)
+			return
+		}
+	}
+
+	if !reflect.DeepEqual(a, b) {
+		*changes = append(*changes, Change{Path: path, Op: Changed, From: a, To: b})
+	}
+}
+
+func walkObject(path string, a, b map[string]interface{}, changes *[]Change) {
+	keys := make([]string, 0, len(a)+len(b))
+	for key := range a {
+		keys = append(keys, key)
+	}
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		child := joinKey(path, key)
+		left, inLeft := a[key]
+		right, inRight := b[key]
+		switch {
+		case !inLeft:
+			*changes = append(*changes, Change{Path: child, Op: Added, To: right})
+		case !inRight:
+			*changes = append(*changes, Change{Path: child, Op: Removed, From: left})
+		default:
+			walk(child, left, right, changes)
+		}
+	}
+}
+
+func walkArray(path string, a, b []interface{}, changes *[]Change) {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		child := fmt.Sprintf("%s[%d]", path, i)
+		switch {
+		case i >= len(a):
+			*changes = append(*changes, Change{Path: child, Op: Added, To: b[i]})
+		case i >= len(b):
+			*changes = append(*changes, Change{Path: child, Op: Removed, From: a[i]})
+		default:
+			walk(child, a[i], b[i], changes)
+		}
+	}
+}
+
+func joinKey(path, key string) string {
+	if path == "" {
+		return key
+	}
+	if strings.ContainsAny(key, ".[]") {
+		return fmt.Sprintf("%s[%q]", path, key)
+	}
+	return path + "." + key
+}
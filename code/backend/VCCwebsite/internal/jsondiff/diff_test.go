@@ -0,0 +1,124 @@
+package jsondiff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	type vital struct {
+		Name  string `json:"name"`
+		Value string `json:"value,omitempty"`
+	}
+
+	tests := []struct {
+		name string
+		a, b interface{}
+		want []Change
+	}{
+		{
+			name: "identical",
+			a:    map[string]interface{}{"a": 1, "b": []int{1, 2}},
+			b:    map[string]interface{}{"a": 1, "b": []int{1, 2}},
+			want: []Change{},
+		},
+		{
+			name: "changed scalar",
+			a:    map[string]interface{}{"a": 1},
+			b:    map[string]interface{}{"a": 2},
+			want: []Change{{Path: "a", Op: Changed, From: 1.0, To: 2.0}},
+		},
+		{
+			name: "added and removed members in path order",
+			a:    map[string]interface{}{"b": "x", "c": true},
+			b:    map[string]interface{}{"a": "y", "c": true},
+			want: []Change{
+				{Path: "a", Op: Added, To: "y"},
+				{Path: "b", Op: Removed, From: "x"},
+			},
+		},
+		{
+			name: "nested object",
+			a:    map[string]interface{}{"med_hist": map[string]interface{}{"allergies": "none"}},
+			b:    map[string]interface{}{"med_hist": map[string]interface{}{"allergies": "nuts"}},
+			want: []Change{{Path: "med_hist.allergies", Op: Changed, From: "none", To: "nuts"}},
+		},
+		{
+			name: "arrays by position",
+			a:    map[string]interface{}{"list": []string{"a", "b", "c"}},
+			b:    map[string]interface{}{"list": []string{"a", "x"}},
+			want: []Change{
+				{Path: "list[1]", Op: Changed, From: "b", To: "x"},
+				{Path: "list[2]", Op: Removed, From: "c"},
+			},
+		},
+		{
+			name: "array grows",
+			a:    []int{1},
+			b:    []int{1, 2},
+			want: []Change{{Path: "[1]", Op: Added, To: 2.0}},
+		},
+		{
+			name: "struct fields inside arrays",
+			a:    []vital{{Name: "HR", Value: "80"}},
+			b:    []vital{{Name: "HR", Value: "90"}},
+			want: []Change{{Path: "[0].value", Op: Changed, From: "80", To: "90"}},
+		},
+		{
+			name: "omitempty field becomes set",
+			a:    vital{Name: "HR"},
+			b:    vital{Name: "HR", Value: "80"},
+			want: []Change{{Path: "value", Op: Added, To: "80"}},
+		},
+		{
+			name: "type change is a single change",
+			a:    map[string]interface{}{"a": map[string]interface{}{"b": 1}},
+			b:    map[string]interface{}{"a": []int{1}},
+			want: []Change{{
+				Path: "a",
+				Op:   Changed,
+				From: map[string]interface{}{"b": 1.0},
+				To:   []interface{}{1.0},
+			}},
+		},
+		{
+			name: "null and missing differ",
+			a:    map[string]interface{}{"a": nil},
+			b:    map[string]interface{}{},
+			want: []Change{{Path: "a", Op: Removed}},
+		},
+		{
+			name: "keys with separators are quoted",
+			a:    map[string]interface{}{"x": map[string]interface{}{"a.b": 1, "c[0]": 1}},
+			b:    map[string]interface{}{"x": map[string]interface{}{"a.b": 2, "c[0]": 2}},
+			want: []Change{
+				{Path: `x["a.b"]`, Op: Changed, From: 1.0, To: 2.0},
+				{Path: `x["c[0]"]`, Op: Changed, From: 1.0, To: 2.0},
+			},
+		},
+		{
+			name: "changed root scalar",
+			a:    "a",
+			b:    "b",
+			want: []Change{{Path: "", Op: Changed, From: "a", To: "b"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Diff(tt.a, tt.b)
+			if err != nil {
+				t.Fatalf("Diff: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diff =\n%#v\nwant\n%#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffUnencodable(t *testing.T) {
+	if _, err := Diff(make(chan int), nil); err == nil {
+		t.Error("Diff of a channel succeeded, want an encoding error")
+	}
+}
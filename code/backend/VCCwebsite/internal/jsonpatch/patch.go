@@ -0,0 +1,290 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalidPatch is returned for malformed patch documents
+	ErrInvalidPatch = errors.New("invalid patch")
+	// ErrPathNotFound is returned when an operation targets a location that does not exist
+	ErrPathNotFound = errors.New("path not found")
+	// ErrTestFailed is returned when a "test" operation does not match
+	ErrTestFailed = errors.New("test operation failed")
+)
+
+// Operation is a single RFC 6902 operation
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// DecodePatch parses an RFC 6902 patch document
+func DecodePatch(patch []byte) ([]Operation, error) {
+	var ops []Operation
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	return ops, nil
+}
+
+// Apply applies the operations to doc in order. Either every operation succeeds
+// or an error is returned and doc is left untouched.
+func Apply(doc []byte, ops []Operation) ([]byte, error) {
+	var root interface{}
+	if err := json.Unmarshal(doc, &root); err != nil {
+		return nil, fmt.Errorf("invalid document: %w", err)
+	}
+
+	for i, op := range ops {
+		var err error
+		root, err = applyOperation(root, op)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d (%s %s): %w", i, op.Op, op.Path, err)
+		}
+	}
+	return json.Marshal(root)
+}
+
+func applyOperation(root interface{}, op Operation) (interface{}, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op.Op {
+	case "add":
+		value, err := decodeValue(op.Value)
+		if err != nil {
+			return nil, err
+		}
+		return add(root, path, value)
+	case "remove":
+		root, _, err := remove(root, path)
+		return root, err
+	case "replace":
+		value, err := decodeValue(op.Value)
+		if err != nil {
+			return nil, err
+		}
+		if root, _, err = remove(root, path); err != nil {
+			return nil, err
+		}
+		return add(root, path, value)
+	case "move":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		if isPrefix(from, path) && len(from) < len(path) {
+			return nil, fmt.Errorf("%w: cannot move a value into one of its children", ErrInvalidPatch)
+		}
+		root, value, err := remove(root, from)
+		if err != nil {
+			return nil, err
+		}
+		return add(root, path, value)
+	case "copy":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		value, err := get(root, from)
+		if err != nil {
+			return nil, err
+		}
+		return add(root, path, deepCopy(value))
+	case "test":
+		value, err := decodeValue(op.Value)
+		if err != nil {
+			return nil, err
+		}
+		current, err := get(root, path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(current, value) {
+			return nil, ErrTestFailed
+		}
+		return root, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown op %q", ErrInvalidPatch, op.Op)
+	}
+}
+
+func decodeValue(raw json.RawMessage) (interface{}, error) {
+	if len(raw) == 0 {
+		return nil, fmt.Errorf("%w: value is required", ErrInvalidPatch)
+	}
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+	return value, nil
+}
+
+// parsePointer splits an RFC 6901 JSON Pointer into unescaped reference tokens
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("%w: pointer %q must start with /", ErrInvalidPatch, pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		token = strings.ReplaceAll(token, "~1", "/")
+		tokens[i] = strings.ReplaceAll(token, "~0", "~")
+	}
+	return tokens, nil
+}
+
+func isPrefix(prefix, path []string) bool {
+	if len(prefix) > len(path) {
+		return false
+	}
+	for i := range prefix {
+		if prefix[i] != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func get(node interface{}, path []string) (interface{}, error) {
+	for _, token := range path {
+		switch container := node.(type) {
+		case map[string]interface{}:
+			child, ok := container[token]
+			if !ok {
+				return nil, ErrPathNotFound
+			}
+			node = child
+		case []interface{}:
+			index, err := arrayIndex(token, len(container)-1)
+			if err != nil {
+				return nil, err
+			}
+			node = container[index]
+		default:
+			return nil, ErrPathNotFound
+		}
+	}
+	return node, nil
+}
+
+// add inserts value at path and returns the (possibly new) root
+func add(root interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	parent, err := get(root, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := path[len(path)-1]
+
+	switch container := parent.(type) {
+	case map[string]interface{}:
+		container[last] = value
+		return root, nil
+	case []interface{}:
+		index := len(container)
+		if last != "-" {
+			if index, err = arrayIndex(last, len(container)); err != nil {
+				return nil, err
+			}
+		}
+		grown := append(container[:index:index], append([]interface{}{value}, container[index:]...)...)
+		return replaceChild(root, path[:len(path)-1], grown)
+	default:
+		return nil, ErrPathNotFound
+	}
+}
+
+// remove deletes the value at path, returning the new root and the removed value
+func remove(root interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, root, nil
+	}
+	parent, err := get(root, path[:len(path)-1])
+	if err != nil {
+		return nil, nil, err
+	}
+	last := path[len(path)-1]
+
+	switch container := parent.(type) {
+	case map[string]interface{}:
+		value, ok := container[last]
+		if !ok {
+			return nil, nil, ErrPathNotFound
+		}
+		delete(container, last)
+		return root, value, nil
+	case []interface{}:
+		index, err := arrayIndex(last, len(container)-1)
+		if err != nil {
+			return nil, nil, err
+		}
+		value := container[index]
+		shrunk := append(container[:index:index], container[index+1:]...)
+		root, err = replaceChild(root, path[:len(path)-1], shrunk)
+		return root, value, err
+	default:
+		return nil, nil, ErrPathNotFound
+	}
+}
+
+// replaceChild stores value at path; needed because resized slices are new values
+func replaceChild(root interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	parent, err := get(root, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := path[len(path)-1]
+	switch container := parent.(type) {
+	case map[string]interface{}:
+		container[last] = value
+	case []interface{}:
+		index, err := arrayIndex(last, len(container)-1)
+		if err != nil {
+			return nil, err
+		}
+		container[index] = value
+	default:
+		return nil, ErrPathNotFound
+	}
+	return root, nil
+}
+
+// arrayIndex parses an array reference token, allowing indexes up to max
+func arrayIndex(token string, max int) (int, error) {
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("%w: invalid array index %q", ErrInvalidPatch, token)
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 {
+		return 0, fmt.Errorf("%w: invalid array index %q", ErrInvalidPatch, token)
+	}
+	if index > max {
+		return 0, ErrPathNotFound
+	}
+	return index, nil
+}
+
+func deepCopy(value interface{}) interface{} {
+	data, _ := json.Marshal(value)
+	var out interface{}
+	_ = json.Unmarshal(data, &out)
+	return out
+}
@@ -0,0 +1,326 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+		err   error
+	}{
+		{
+			name:  "add member",
+			doc:   `{"a":1}`,
+			patch: `[{"op":"add","path":"/b","value":2}]`,
+			want:  `{"a":1,"b":2}`,
+		},
+		{
+			name:  "add replaces existing member",
+			doc:   `{"a":1}`,
+			patch: `[{"op":"add","path":"/a","value":[1]}]`,
+			want:  `{"a":[1]}`,
+		},
+		{
+			name:  "add inserts into array",
+			doc:   `{"a":[1,3]}`,
+			patch: `[{"op":"add","path":"/a/1","value":2}]`,
+			want:  `{"a":[1,2,3]}`,
+		},
+		{
+			name:  "add at array length appends",
+			doc:   `{"a":[1]}`,
+			patch: `[{"op":"add","path":"/a/1","value":2}]`,
+			want:  `{"a":[1,2]}`,
+		},
+		{
+			name:  "add with dash appends",
+			doc:   `{"a":[1,2]}`,
+			patch: `[{"op":"add","path":"/a/-","value":3}]`,
+			want:  `{"a":[1,2,3]}`,
+		},
+		{
+			name:  "add with dash to empty array",
+			doc:   `{"a":[]}`,
+			patch: `[{"op":"add","path":"/a/-","value":{"b":1}}]`,
+			want:  `{"a":[{"b":1}]}`,
+		},
+		{
+			name:  "add past array end",
+			doc:   `{"a":[1]}`,
+			patch: `[{"op":"add","path":"/a/2","value":2}]`,
+			err:   ErrPathNotFound,
+		},
+		{
+			name:  "add under missing parent",
+			doc:   `{}`,
+			patch: `[{"op":"add","path":"/a/b","value":1}]`,
+			err:   ErrPathNotFound,
+		},
+		{
+			name:  "add without value",
+			doc:   `{}`,
+			patch: `[{"op":"add","path":"/a"}]`,
+			err:   ErrInvalidPatch,
+		},
+		{
+			name:  "add null value",
+			doc:   `{}`,
+			patch: `[{"op":"add","path":"/a","value":null}]`,
+			want:  `{"a":null}`,
+		},
+		{
+			name:  "add replaces root",
+			doc:   `{"a":1}`,
+			patch: `[{"op":"add","path":"","value":[1]}]`,
+			want:  `[1]`,
+		},
+		{
+			name:  "tilde one escapes slash",
+			doc:   `{"a/b":1}`,
+			patch: `[{"op":"replace","path":"/a~1b","value":2}]`,
+			want:  `{"a/b":2}`,
+		},
+		{
+			name:  "tilde zero escapes tilde",
+			doc:   `{"m~n":1}`,
+			patch: `[{"op":"remove","path":"/m~0n"}]`,
+			want:  `{}`,
+		},
+		{
+			name:  "tilde zero one is a literal tilde one",
+			doc:   `{"~1":1,"/":2}`,
+			patch: `[{"op":"remove","path":"/~01"}]`,
+			want:  `{"/":2}`,
+		},
+		{
+			name:  "empty member name",
+			doc:   `{"":1}`,
+			patch: `[{"op":"replace","path":"/","value":2}]`,
+			want:  `{"":2}`,
+		},
+		{
+			name:  "pointer without leading slash",
+			doc:   `{"a":1}`,
+			patch: `[{"op":"remove","path":"a"}]`,
+			err:   ErrInvalidPatch,
+		},
+		{
+			name:  "remove array element",
+			doc:   `{"a":[1,2,3]}`,
+			patch: `[{"op":"remove","path":"/a/1"}]`,
+			want:  `{"a":[1,3]}`,
+		},
+		{
+			name:  "remove with dash",
+			doc:   `{"a":[1]}`,
+			patch: `[{"op":"remove","path":"/a/-"}]`,
+			err:   ErrInvalidPatch,
+		},
+		{
+			name:  "remove missing member",
+			doc:   `{"a":1}`,
+			patch: `[{"op":"remove","path":"/b"}]`,
+			err:   ErrPathNotFound,
+		},
+		{
+			name:  "leading zero index",
+			doc:   `{"a":[1,2]}`,
+			patch: `[{"op":"remove","path":"/a/01"}]`,
+			err:   ErrInvalidPatch,
+		},
+		{
+			name:  "negative index",
+			doc:   `{"a":[1,2]}`,
+			patch: `[{"op":"remove","path":"/a/-1"}]`,
+			err:   ErrInvalidPatch,
+		},
+		{
+			name:  "replace missing member",
+			doc:   `{"a":1}`,
+			patch: `[{"op":"replace","path":"/b","value":2}]`,
+			err:   ErrPathNotFound,
+		},
+		{
+			name:  "replace array element keeps position",
+			doc:   `{"a":[1,2,3]}`,
+			patch: `[{"op":"replace","path":"/a/1","value":9}]`,
+			want:  `{"a":[1,9,3]}`,
+		},
+		{
+			name:  "move member",
+			doc:   `{"a":{"b":1},"c":{}}`,
+			patch: `[{"op":"move","from":"/a/b","path":"/c/d"}]`,
+			want:  `{"a":{},"c":{"d":1}}`,
+		},
+		{
+			name:  "move array element",
+			doc:   `{"a":[1,2,3]}`,
+			patch: `[{"op":"move","from":"/a/0","path":"/a/-"}]`,
+			want:  `{"a":[2,3,1]}`,
+		},
+		{
+			name:  "move onto itself",
+			doc:   `{"a":1}`,
+			patch: `[{"op":"move","from":"/a","path":"/a"}]`,
+			want:  `{"a":1}`,
+		},
+		{
+			name:  "move into own child",
+			doc:   `{"a":{"b":{}}}`,
+			patch: `[{"op":"move","from":"/a","path":"/a/b/c"}]`,
+			err:   ErrInvalidPatch,
+		},
+		{
+			name:  "move to sibling with shared prefix",
+			doc:   `{"a":1}`,
+			patch: `[{"op":"move","from":"/a","path":"/ab"}]`,
+			want:  `{"ab":1}`,
+		},
+		{
+			name:  "move from missing",
+			doc:   `{}`,
+			patch: `[{"op":"move","from":"/a","path":"/b"}]`,
+			err:   ErrPathNotFound,
+		},
+		{
+			name:  "copy is independent of its source",
+			doc:   `{"a":{"b":1}}`,
+			patch: `[{"op":"copy","from":"/a","path":"/c"},{"op":"replace","path":"/c/b","value":2}]`,
+			want:  `{"a":{"b":1},"c":{"b":2}}`,
+		},
+		{
+			name:  "copy into array",
+			doc:   `{"a":[1,2]}`,
+			patch: `[{"op":"copy","from":"/a/1","path":"/a/0"}]`,
+			want:  `{"a":[2,1,2]}`,
+		},
+		{
+			name:  "copy from missing",
+			doc:   `{}`,
+			patch: `[{"op":"copy","from":"/a","path":"/b"}]`,
+			err:   ErrPathNotFound,
+		},
+		{
+			name:  "test passes",
+			doc:   `{"a":{"b":[1,"x",null]}}`,
+			patch: `[{"op":"test","path":"/a","value":{"b":[1,"x",null]}}]`,
+			want:  `{"a":{"b":[1,"x",null]}}`,
+		},
+		{
+			name:  "test null value",
+			doc:   `{"a":null}`,
+			patch: `[{"op":"test","path":"/a","value":null}]`,
+			want:  `{"a":null}`,
+		},
+		{
+			name:  "test number and string differ",
+			doc:   `{"a":1}`,
+			patch: `[{"op":"test","path":"/a","value":"1"}]`,
+			err:   ErrTestFailed,
+		},
+		{
+			name:  "test missing member",
+			doc:   `{}`,
+			patch: `[{"op":"test","path":"/a","value":null}]`,
+			err:   ErrPathNotFound,
+		},
+		{
+			name:  "failed test stops later operations",
+			doc:   `{"a":1}`,
+			patch: `[{"op":"replace","path":"/a","value":2},{"op":"test","path":"/a","value":1}]`,
+			err:   ErrTestFailed,
+		},
+		{
+			name:  "unknown op",
+			doc:   `{}`,
+			patch: `[{"op":"merge","path":"/a","value":1}]`,
+			err:   ErrInvalidPatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ops, err := DecodePatch([]byte(tt.patch))
+			if err != nil {
+				t.Fatalf("DecodePatch: %v", err)
+			}
+			got, err := Apply([]byte(tt.doc), ops)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("Apply error = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Apply: %v", err)
+			}
+			assertJSONEqual(t, got, tt.want)
+		})
+	}
+}
+
+func TestDecodePatchInvalid(t *testing.T) {
+	for _, patch := range []string{`{"op":"add"}`, `not json`, `[{"op":1}]`} {
+		if _, err := DecodePatch([]byte(patch)); !errors.Is(err, ErrInvalidPatch) {
+			t.Errorf("DecodePatch(%s) error = %v, want ErrInvalidPatch", patch, err)
+		}
+	}
+}
+
+func TestMergePatch(t *testing.T) {
+	// Cases from RFC 7396 appendix A
+	tests := []struct {
+		doc, patch, want string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+		{``, `{"a":1}`, `{"a":1}`},
+	}
+
+	for _, tt := range tests {
+		got, err := MergePatch([]byte(tt.doc), []byte(tt.patch))
+		if err != nil {
+			t.Errorf("MergePatch(%s, %s): %v", tt.doc, tt.patch, err)
+			continue
+		}
+		assertJSONEqual(t, got, tt.want)
+	}
+
+	if _, err := MergePatch([]byte(`{}`), []byte(`{`)); !errors.Is(err, ErrInvalidPatch) {
+		t.Errorf("MergePatch with malformed patch error = %v, want ErrInvalidPatch", err)
+	}
+}
+
+func assertJSONEqual(t *testing.T, got []byte, want string) {
+	t.Helper()
+	var g, w interface{}
+	if err := json.Unmarshal(got, &g); err != nil {
+		t.Fatalf("result %s is not JSON: %v", got, err)
+	}
+	if err := json.Unmarshal([]byte(want), &w); err != nil {
+		t.Fatalf("expected %s is not JSON: %v", want, err)
+	}
+	if !reflect.DeepEqual(g, w) {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,26 @@
+package measurement
+
+// BMI returns the body mass index for a weight and height in kg/m², or 0 when
+// either is missing
+func BMI(w Weight, h Height) float64 {
+	meters := h.Meters()
+	if w.Reading <= 0 || meters <= 0 {
+		return 0
+	}
+	return w.Kilograms() / (meters * meters)
+}
+
+// BMICategory returns the adult WHO category of a body mass index
+func BMICategory(bmi float64) string {
+	switch {
+	case bmi <= 0:
+		return ""
+	case bmi < 18.5:
+		return "Underweight"
+	case bmi < 25:
+		return "Normal"
+	case bmi < 30:
+		return "Overweight"
+	}
+	return "Obese"
+}
@@ -0,0 +1,69 @@
+package measurement
+
+import "go.mongodb.org/mongo-driver/bson/bsontype"
+
+// GlucoseUnit is the unit of a Glucose reading
+type GlucoseUnit int8
+
+const (
+	MilligramsPerDeciliter GlucoseUnit = iota
+	MillimolesPerLiter
+)
+
+// glucoseMgPerDlPerMmol converts mmol/L to mg/dL using the molar mass of
+// glucose, 180.156 g/mol
+const glucoseMgPerDlPerMmol = 18.0156
+
+var glucoseUnits = unitTable{kind: "glucose", units: []unit{
+	{name: "mg/dL", symbol: "mg/dL", aliases: []string{"mg/dl", "mgdl"}, decimals: 0, factor: 1},
+	{name: "mmol/L", symbol: "mmol/L", aliases: []string{"mmol/l", "mmol"}, decimals: 1, factor: glucoseMgPerDlPerMmol},
+}}
+
+func (u GlucoseUnit) String() string { return glucoseUnits.get(int(u)).name }
+
+// Symbol returns the display symbol of the unit, e.g. mg/dL
+func (u GlucoseUnit) Symbol() string { return glucoseUnits.get(int(u)).symbol }
+
+func (u GlucoseUnit) MarshalJSON() ([]byte, error) { return glucoseUnits.marshalJSON(int(u)) }
+
+func (u *GlucoseUnit) UnmarshalJSON(data []byte) error {
+	code, err := glucoseUnits.unmarshalJSON(data)
+	*u = GlucoseUnit(code)
+	return err
+}
+
+func (u GlucoseUnit) MarshalBSONValue() (bsontype.Type, []byte, error) {
+	return glucoseUnits.marshalBSONValue(int(u))
+}
+
+func (u *GlucoseUnit) UnmarshalBSONValue(bt bsontype.Type, data []byte) error {
+	code, err := glucoseUnits.unmarshalBSONValue(bt, data)
+	*u = GlucoseUnit(code)
+	return err
+}
+
+// Glucose is a blood glucose reading in a given unit
+type Glucose struct {
+	Reading float64     `json:"reading"`
+	Unit    GlucoseUnit `json:"unit"`
+}
+
+// In returns the reading converted to unit
+func (g Glucose) In(unit GlucoseUnit) Glucose {
+	return Glucose{Reading: glucoseUnits.convert(g.Reading, int(g.Unit), int(unit)), Unit: unit}
+}
+
+// MgPerDl returns the reading in mg/dL
+func (g Glucose) MgPerDl() float64 { return g.In(MilligramsPerDeciliter).Reading }
+
+// String formats the reading in its own unit, e.g. "95 mg/dL"
+func (g Glucose) String() string { return glucoseUnits.format(g.Reading, int(g.Unit)) }
+
+// Display formats the reading in its own unit followed by the other one
+func (g Glucose) Display() string {
+	other := MillimolesPerLiter
+	if g.Unit == MillimolesPerLiter {
+		other = MilligramsPerDeciliter
+	}
+	return g.String() + " / " + g.In(other).String()
+}
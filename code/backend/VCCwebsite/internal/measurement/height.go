@@ -0,0 +1,68 @@
+package measurement
+
+import "go.mongodb.org/mongo-driver/bson/bsontype"
+
+// HeightUnit is the unit of a Height reading
+type HeightUnit int8
+
+const (
+	Centimeters HeightUnit = iota
+	Inches
+)
+
+// centimetersPerInch is exact by the international definition of the inch
+const centimetersPerInch = 2.54
+
+var heightUnits = unitTable{kind: "height", units: []unit{
+	{name: "Centimeters", symbol: "cm", aliases: []string{"centimeter", "centimetres", "centimetre"}, decimals: 0, factor: 1},
+	{name: "Inches", symbol: "in", aliases: []string{"inch", `"`}, decimals: 1, factor: centimetersPerInch},
+}}
+
+func (u HeightUnit) String() string { return heightUnits.get(int(u)).name }
+
+// Symbol returns the display symbol of the unit, e.g. cm
+func (u HeightUnit) Symbol() string { return heightUnits.get(int(u)).symbol }
+
+func (u HeightUnit) MarshalJSON() ([]byte, error) { return heightUnits.marshalJSON(int(u)) }
+
+func (u *HeightUnit) UnmarshalJSON(data []byte) error {
+	code, err := heightUnits.unmarshalJSON(data)
+	*u = HeightUnit(code)
+	return err
+}
+
+func (u HeightUnit) MarshalBSONValue() (bsontype.Type, []byte, error) {
+	return heightUnits.marshalBSONValue(int(u))
+}
+
+func (u *HeightUnit) UnmarshalBSONValue(bt bsontype.Type, data []byte) error {
+	code, err := heightUnits.unmarshalBSONValue(bt, data)
+	*u = HeightUnit(code)
+	return err
+}
+
+// Height is a body height reading in a given unit
+type Height struct {
+	Reading float64    `json:"reading"`
+	Unit    HeightUnit `json:"unit"`
+}
+
+// In returns the height converted to unit
+func (h Height) In(unit HeightUnit) Height {
+	return Height{Reading: heightUnits.convert(h.Reading, int(h.Unit), int(unit)), Unit: unit}
+}
+
+// Meters returns the reading in meters
+func (h Height) Meters() float64 { return h.In(Centimeters).Reading / 100 }
+
+// String formats the reading in its own unit, e.g. "175 cm"
+func (h Height) String() string { return heightUnits.format(h.Reading, int(h.Unit)) }
+
+// Display formats the reading in its own unit followed by the other one
+func (h Height) Display() string {
+	other := Inches
+	if h.Unit == Inches {
+		other = Centimeters
+	}
+	return h.String() + " / " + h.In(other).String()
+}
@@ -0,0 +1,197 @@
+package measurement
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func near(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+
+func TestConversions(t *testing.T) {
+	tests := []struct {
+		name      string
+		got, want float64
+	}{
+		{"freezing to Fahrenheit", Temperature{0, Celsius}.Fahrenheit(), 32},
+		{"boiling to Fahrenheit", Temperature{100, Celsius}.Fahrenheit(), 212},
+		{"body temperature to Celsius", Temperature{98.6, Fahrenheit}.Celsius(), 37},
+		{"minus forty is the same", Temperature{-40, Fahrenheit}.Celsius(), -40},
+		{"Celsius unchanged", Temperature{37.2, Celsius}.Celsius(), 37.2},
+		{"pound to kilograms", Weight{1, Pounds}.Kilograms(), 0.45359237},
+		{"kilograms to pounds", Weight{0.45359237, Kilograms}.In(Pounds).Reading, 1},
+		{"kilograms unchanged", Weight{70, Kilograms}.Kilograms(), 70},
+		{"inch to centimeters", Height{1, Inches}.In(Centimeters).Reading, 2.54},
+		{"centimeters to inches", Height{254, Centimeters}.In(Inches).Reading, 100},
+		{"inches to meters", Height{100, Inches}.Meters(), 2.54},
+		{"mmol/L to mg/dL", Glucose{5.5, MillimolesPerLiter}.MgPerDl(), 99.0858},
+		{"mg/dL to mmol/L", Glucose{180.156, MilligramsPerDeciliter}.In(MillimolesPerLiter).Reading, 10},
+		{"unknown unit code reads as base unit", Weight{70, WeightUnit(9)}.Kilograms(), 70},
+	}
+	for _, tt := range tests {
+		if !near(tt.got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, c := range []float64{-40, 0, 36.6, 41.3} {
+		if got := (Temperature{c, Celsius}).In(Fahrenheit).Celsius(); !near(got, c) {
+			t.Errorf("temperature %v round trip = %v", c, got)
+		}
+	}
+	for _, kg := range []float64{3.2, 70, 180.5} {
+		if got := (Weight{kg, Kilograms}).In(Pounds).Kilograms(); !near(got, kg) {
+			t.Errorf("weight %v round trip = %v", kg, got)
+		}
+	}
+	for _, mg := range []float64{40, 95, 600} {
+		if got := (Glucose{mg, MilligramsPerDeciliter}).In(MillimolesPerLiter).MgPerDl(); !near(got, mg) {
+			t.Errorf("glucose %v round trip = %v", mg, got)
+		}
+	}
+}
+
+func TestDisplay(t *testing.T) {
+	tests := []struct{ got, want string }{
+		{Temperature{37, Celsius}.Display(), "37.0 °C / 98.6 °F"},
+		{Temperature{101.3, Fahrenheit}.Display(), "101.3 °F / 38.5 °C"},
+		{Weight{70, Kilograms}.Display(), "70.0 kg / 154.3 lb"},
+		{Weight{154, Pounds}.Display(), "154.0 lb / 69.9 kg"},
+		{Height{175, Centimeters}.Display(), "175 cm / 68.9 in"},
+		{Height{70, Inches}.Display(), "70.0 in / 178 cm"},
+		{Glucose{95, MilligramsPerDeciliter}.Display(), "95 mg/dL / 5.3 mmol/L"},
+		{Glucose{7.8, MillimolesPerLiter}.Display(), "7.8 mmol/L / 141 mg/dL"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("got %q, want %q", tt.got, tt.want)
+		}
+	}
+}
+
+func TestParseTemperatureUnit(t *testing.T) {
+	tests := []struct {
+		in   string
+		want TemperatureUnit
+		ok   bool
+	}{
+		{"", Celsius, true},
+		{"Celsius", Celsius, true},
+		{"celcius", Celsius, true},
+		{"°C", Celsius, true},
+		{" f ", Fahrenheit, true},
+		{"°F", Fahrenheit, true},
+		{"1", Fahrenheit, true},
+		{"2", Celsius, false},
+		{"Kelvin", Celsius, false},
+	}
+	for _, tt := range tests {
+		got, err := ParseTemperatureUnit(tt.in)
+		if (err == nil) != tt.ok || (tt.ok && got != tt.want) {
+			t.Errorf("ParseTemperatureUnit(%q) = %v, %v; want %v, ok=%v", tt.in, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestUnitJSON(t *testing.T) {
+	data, err := json.Marshal(Weight{Reading: 150, Unit: Pounds})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"reading":150,"unit":"Pounds"}` {
+		t.Errorf("Marshal = %s", data)
+	}
+
+	tests := []struct {
+		in   string
+		want WeightUnit
+		ok   bool
+	}{
+		{`{"unit":"Pounds"}`, Pounds, true},
+		{`{"unit":"lbs"}`, Pounds, true},
+		{`{"unit":"KG"}`, Kilograms, true},
+		{`{"unit":1}`, Pounds, true}, // numeric code written by earlier versions
+		{`{"unit":null}`, Kilograms, true},
+		{`{}`, Kilograms, true},
+		{`{"unit":1.5}`, Kilograms, false},
+		{`{"unit":7}`, Kilograms, false},
+		{`{"unit":"stone"}`, Kilograms, false},
+		{`{"unit":true}`, Kilograms, false},
+	}
+	for _, tt := range tests {
+		var w Weight
+		err := json.Unmarshal([]byte(tt.in), &w)
+		if (err == nil) != tt.ok || (tt.ok && w.Unit != tt.want) {
+			t.Errorf("Unmarshal(%s) = %v, %v; want %v, ok=%v", tt.in, w.Unit, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestUnitBSON(t *testing.T) {
+	data, err := bson.Marshal(Glucose{Reading: 6.1, Unit: MillimolesPerLiter})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var raw bson.M
+	if err := bson.Unmarshal(data, &raw); err != nil {
+		t.Fatal(err)
+	}
+	if raw["unit"] != "mmol/L" {
+		t.Errorf("stored unit = %v, want mmol/L", raw["unit"])
+	}
+
+	tests := []struct {
+		doc  bson.M
+		want GlucoseUnit
+		ok   bool
+	}{
+		{bson.M{"unit": "mmol/L"}, MillimolesPerLiter, true},
+		{bson.M{"unit": int32(1)}, MillimolesPerLiter, true},
+		{bson.M{"unit": int64(0)}, MilligramsPerDeciliter, true},
+		{bson.M{"unit": 1.0}, MillimolesPerLiter, true},
+		{bson.M{"unit": nil}, MilligramsPerDeciliter, true},
+		{bson.M{"unit": int32(5)}, MilligramsPerDeciliter, false},
+		{bson.M{"unit": true}, MilligramsPerDeciliter, false},
+	}
+	for _, tt := range tests {
+		data, err := bson.Marshal(tt.doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var g Glucose
+		err = bson.Unmarshal(data, &g)
+		if (err == nil) != tt.ok || (tt.ok && g.Unit != tt.want) {
+			t.Errorf("Unmarshal(%v) = %v, %v; want %v, ok=%v", tt.doc, g.Unit, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestBMI(t *testing.T) {
+	tests := []struct {
+		name     string
+		w        Weight
+		h        Height
+		want     float64
+		category string
+	}{
+		{"metric", Weight{70, Kilograms}, Height{175, Centimeters}, 22.857142857, "Normal"},
+		{"imperial", Weight{220, Pounds}, Height{70, Inches}, 31.566389, "Obese"},
+		{"underweight", Weight{50, Kilograms}, Height{180, Centimeters}, 15.432099, "Underweight"},
+		{"overweight", Weight{85, Kilograms}, Height{175, Centimeters}, 27.755102, "Overweight"},
+		{"missing weight", Weight{}, Height{175, Centimeters}, 0, ""},
+		{"missing height", Weight{70, Kilograms}, Height{}, 0, ""},
+	}
+	for _, tt := range tests {
+		bmi := BMI(tt.w, tt.h)
+		if math.Abs(bmi-tt.want) > 1e-5 {
+			t.Errorf("%s: BMI = %v, want %v", tt.name, bmi, tt.want)
+		}
+		if got := BMICategory(bmi); got != tt.category {
+			t.Errorf("%s: BMICategory(%v) = %q, want %q", tt.name, bmi, got, tt.category)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package measurement
+
+import "go.mongodb.org/mongo-driver/bson/bsontype"
+
+// TemperatureUnit is the unit of a Temperature reading
+type TemperatureUnit int8
+
+const (
+	Celsius TemperatureUnit = iota
+	Fahrenheit
+)
+
+var temperatureUnits = unitTable{kind: "temperature", units: []unit{
+	{name: "Celsius", symbol: "°C", aliases: []string{"Celcius", "C"}, decimals: 1, factor: 1},
+	{name: "Fahrenheit", symbol: "°F", aliases: []string{"F"}, decimals: 1,
+		toBase:   func(f float64) float64 { return (f - 32) * 5 / 9 },
+		fromBase: func(c float64) float64 { return c*9/5 + 32 },
+	},
+}}
+
+func (u TemperatureUnit) String() string { return temperatureUnits.get(int(u)).name }
+
+// Symbol returns the display symbol of the unit, e.g. °C
+func (u TemperatureUnit) Symbol() string { return temperatureUnits.get(int(u)).symbol }
+
+func (u TemperatureUnit) MarshalJSON() ([]byte, error) { return temperatureUnits.marshalJSON(int(u)) }
+
+func (u *TemperatureUnit) UnmarshalJSON(data []byte) error {
+	code, err := temperatureUnits.unmarshalJSON(data)
+	*u = TemperatureUnit(code)
+	return err
+}
+
+func (u TemperatureUnit) MarshalBSONValue() (bsontype.Type, []byte, error) {
+	return temperatureUnits.marshalBSONValue(int(u))
+}
+
+func (u *TemperatureUnit) UnmarshalBSONValue(bt bsontype.Type, data []byte) error {
+	code, err := temperatureUnits.unmarshalBSONValue(bt, data)
+	*u = TemperatureUnit(code)
+	return err
+}
+
+// ParseTemperatureUnit resolves a unit name such as "Fahrenheit", "F" or "°F"
+func ParseTemperatureUnit(s string) (TemperatureUnit, error) {
+	code, err := temperatureUnits.parse(s)
+	return TemperatureUnit(code), err
+}
+
+// Temperature is a temperature reading in a given unit
+type Temperature struct {
+	Reading float64         `json:"reading"`
+	Unit    TemperatureUnit `json:"unit"`
+}
+
+// In returns the temperature converted to unit
+func (t Temperature) In(unit TemperatureUnit) Temperature {
+	return Temperature{Reading: temperatureUnits.convert(t.Reading, int(t.Unit), int(unit)), Unit: unit}
+}
+
+// Celsius returns the reading in degrees Celsius
+func (t Temperature) Celsius() float64 { return t.In(Celsius).Reading }
+
+// Fahrenheit returns the reading in degrees Fahrenheit
+func (t Temperature) Fahrenheit() float64 { return t.In(Fahrenheit).Reading }
+
+// String formats the reading in its own unit, e.g. "37.0 °C"
+func (t Temperature) String() string { return temperatureUnits.format(t.Reading, int(t.Unit)) }
+
+// Display formats the reading in its own unit followed by the other one,
+// e.g. "37.0 °C / 98.6 °F"
+func (t Temperature) Display() string {
+	other := Fahrenheit
+	if t.Unit == Fahrenheit {
+		other = Celsius
+	}
+	return t.String() + " / " + t.In(other).String()
+}
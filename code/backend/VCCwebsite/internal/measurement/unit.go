@@ -0,0 +1,139 @@
+// Package measurement holds the unit-aware quantities used in scripts: temperature,
+// weight, height and blood glucose. Units marshal to their names in JSON and BSON and
+// also accept the numeric codes written by earlier versions.
+package measurement
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+)
+
+// unit describes one unit of a kind of measurement. Readings are converted through
+// the base unit of their kind, which is always the unit at index 0: a reading in this
+// unit is factor base units, unless toBase and fromBase are set for affine scales.
+type unit struct {
+	name     string   // name used in JSON and BSON
+	symbol   string   // symbol used for display
+	aliases  []string // other accepted spellings, compared case-insensitively
+	decimals int      // decimals shown when displaying a reading
+	factor   float64
+	toBase   func(float64) float64
+	fromBase func(float64) float64
+}
+
+func (u unit) base(v float64) float64 {
+	if u.toBase != nil {
+		return u.toBase(v)
+	}
+	return v * u.factor
+}
+
+func (u unit) fromBaseValue(v float64) float64 {
+	if u.fromBase != nil {
+		return u.fromBase(v)
+	}
+	return v / u.factor
+}
+
+// unitTable lists the units of one kind, indexed by their numeric code
+type unitTable struct {
+	kind  string
+	units []unit
+}
+
+func (t unitTable) get(code int) unit {
+	if code < 0 || code >= len(t.units) {
+		return t.units[0]
+	}
+	return t.units[code]
+}
+
+func (t unitTable) convert(value float64, from, to int) float64 {
+	if from == to {
+		return value
+	}
+	return t.get(to).fromBaseValue(t.get(from).base(value))
+}
+
+func (t unitTable) format(value float64, code int) string {
+	u := t.get(code)
+	return strconv.FormatFloat(value, 'f', u.decimals, 64) + " " + u.symbol
+}
+
+// parse resolves a unit name, symbol, alias or numeric code
+func (t unitTable) parse(s string) (int, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	if n, err := strconv.Atoi(s); err == nil {
+		return t.code(int64(n))
+	}
+	for i, u := range t.units {
+		if strings.EqualFold(s, u.name) || strings.EqualFold(s, u.symbol) {
+			return i, nil
+		}
+		for _, alias := range u.aliases {
+			if strings.EqualFold(s, alias) {
+				return i, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("measurement: unknown %s unit %q", t.kind, s)
+}
+
+func (t unitTable) code(n int64) (int, error) {
+	if n < 0 || n >= int64(len(t.units)) {
+		return 0, fmt.Errorf("measurement: unknown %s unit code %d", t.kind, n)
+	}
+	return int(n), nil
+}
+
+func (t unitTable) marshalJSON(code int) ([]byte, error) {
+	return json.Marshal(t.get(code).name)
+}
+
+func (t unitTable) unmarshalJSON(data []byte) (int, error) {
+	var raw interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return 0, err
+	}
+	switch v := raw.(type) {
+	case nil:
+		return 0, nil
+	case string:
+		return t.parse(v)
+	case float64:
+		if v != float64(int64(v)) {
+			return 0, fmt.Errorf("measurement: unknown %s unit code %v", t.kind, v)
+		}
+		return t.code(int64(v))
+	}
+	return 0, fmt.Errorf("measurement: %s unit must be a string", t.kind)
+}
+
+func (t unitTable) marshalBSONValue(code int) (bsontype.Type, []byte, error) {
+	return bson.MarshalValue(t.get(code).name)
+}
+
+func (t unitTable) unmarshalBSONValue(bt bsontype.Type, data []byte) (int, error) {
+	raw := bson.RawValue{Type: bt, Value: data}
+	switch bt {
+	case bsontype.Null, bsontype.Undefined:
+		return 0, nil
+	case bsontype.String:
+		return t.parse(raw.StringValue())
+	case bsontype.Int32:
+		return t.code(int64(raw.Int32()))
+	case bsontype.Int64:
+		return t.code(raw.Int64())
+	case bsontype.Double:
+		return t.code(int64(raw.Double()))
+	}
+	return 0, fmt.Errorf("measurement: cannot decode %s unit from BSON %s", t.kind, bt)
+}
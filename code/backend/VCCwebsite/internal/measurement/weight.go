@@ -0,0 +1,68 @@
+package measurement
+
+import "go.mongodb.org/mongo-driver/bson/bsontype"
+
+// WeightUnit is the unit of a Weight reading
+type WeightUnit int8
+
+const (
+	Kilograms WeightUnit = iota
+	Pounds
+)
+
+// kilogramsPerPound is exact by the international definition of the pound
+const kilogramsPerPound = 0.45359237
+
+var weightUnits = unitTable{kind: "weight", units: []unit{
+	{name: "Kilograms", symbol: "kg", aliases: []string{"kilogram", "kgs"}, decimals: 1, factor: 1},
+	{name: "Pounds", symbol: "lb", aliases: []string{"pound", "lbs"}, decimals: 1, factor: kilogramsPerPound},
+}}
+
+func (u WeightUnit) String() string { return weightUnits.get(int(u)).name }
+
+// Symbol returns the display symbol of the unit, e.g. kg
+func (u WeightUnit) Symbol() string { return weightUnits.get(int(u)).symbol }
+
+func (u WeightUnit) MarshalJSON() ([]byte, error) { return weightUnits.marshalJSON(int(u)) }
+
+func (u *WeightUnit) UnmarshalJSON(data []byte) error {
+	code, err := weightUnits.unmarshalJSON(data)
+	*u = WeightUnit(code)
+	return err
+}
+
+func (u WeightUnit) MarshalBSONValue() (bsontype.Type, []byte, error) {
+	return weightUnits.marshalBSONValue(int(u))
+}
+
+func (u *WeightUnit) UnmarshalBSONValue(bt bsontype.Type, data []byte) error {
+	code, err := weightUnits.unmarshalBSONValue(bt, data)
+	*u = WeightUnit(code)
+	return err
+}
+
+// Weight is a body weight reading in a given unit
+type Weight struct {
+	Reading float64    `json:"reading"`
+	Unit    WeightUnit `json:"unit"`
+}
+
+// In returns the weight converted to unit
+func (w Weight) In(unit WeightUnit) Weight {
+	return Weight{Reading: weightUnits.convert(w.Reading, int(w.Unit), int(unit)), Unit: unit}
+}
+
+// Kilograms returns the reading in kilograms
+func (w Weight) Kilograms() float64 { return w.In(Kilograms).Reading }
+
+// String formats the reading in its own unit, e.g. "70.0 kg"
+func (w Weight) String() string { return weightUnits.format(w.Reading, int(w.Unit)) }
+
+// Display formats the reading in its own unit followed by the other one
+func (w Weight) Display() string {
+	other := Pounds
+	if w.Unit == Pounds {
+		other = Kilograms
+	}
+	return w.String() + " / " + w.In(other).String()
+}
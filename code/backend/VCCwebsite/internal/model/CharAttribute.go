@@ -1,14 +1,14 @@
 package scripts
 
 type emotions struct {
-	Anxiety       uint8 `json:"anxiety"`
-	Suprise       uint8 `json:"suprise"`
-	Confusion     uint8 `json:"confusion"`
-	Guilt         uint8 `json:"guilt"`
-	Sadness       uint8 `json:"sadness"`
-	Indecision    uint8 `json:"indecision"`
-	Assertiveness uint8 `json:"assertiveness"`
-	Frustration   uint8 `json:"frustration"`
-	Fear          uint8 `json:"fear"`
-	Anger         uint8 `json:"anger"`
+	Anxiety       uint8 `json:"anxiety" bson:"anxiety"`
+	Suprise       uint8 `json:"suprise" bson:"suprise"`
+	Confusion     uint8 `json:"confusion" bson:"confusion"`
+	Guilt         uint8 `json:"guilt" bson:"guilt"`
+	Sadness       uint8 `json:"sadness" bson:"sadness"`
+	Indecision    uint8 `json:"indecision" bson:"indecision"`
+	Assertiveness uint8 `json:"assertiveness" bson:"assertiveness"`
+	Frustration   uint8 `json:"frustration" bson:"frustration"`
+	Fear          uint8 `json:"fear" bson:"fear"`
+	Anger         uint8 `json:"anger" bson:"anger"`
 }
@@ -1,10 +1,12 @@
 package scripts
 
 type Artifact struct {
-	ID          string `json:"id"`
-	Name        string `json:"name"`
-	ContentType string `json:"content_type"`
-	Size        int64  `json:"size"`
-	UploadedAt  string `json:"uploaded_at"`
-	URL         string `json:"url,omitempty"`
+	ID           string `json:"id" bson:"id"`
+	Name         string `json:"name" bson:"name"`
+	ContentType  string `json:"content_type" bson:"content_type"`
+	Size         int64  `json:"size" bson:"size"`
+	UploadedAt   string `json:"uploaded_at" bson:"uploaded_at"`
+	UploadedBy   string `json:"uploaded_by,omitempty" bson:"uploaded_by,omitempty"`
+	URL          string `json:"url,omitempty" bson:"url,omitempty"`
+	ThumbnailURL string `json:"thumbnail_url,omitempty" bson:"thumbnail_url,omitempty"`
 }
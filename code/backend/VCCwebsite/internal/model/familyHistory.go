@@ -1,8 +1,8 @@
 package scripts
 
 type FamilyHistory struct {
-	HealthStatus  string `json:"health_status"`
-	Age           uint8  `json:"age"`
-	CauseOfDeath  string `json:"cause_of_death"`
-	AdditonalInfo string `json:"additonal_info"`
+	HealthStatus  string `json:"health_status" bson:"health_status"`
+	Age           uint8  `json:"age" bson:"age"`
+	CauseOfDeath  string `json:"cause_of_death" bson:"cause_of_death"`
+	AdditonalInfo string `json:"additonal_info" bson:"additonal_info"`
 }
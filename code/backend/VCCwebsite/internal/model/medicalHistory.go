@@ -1,11 +1,11 @@
 package scripts
 
 type MedicalHistory struct {
-	Medications         []MedicationCard     `json:"medications"`
-	Allergies           string               `json:"allergies"`
-	PastMedHis          PastMedicalHistory   `json:"past_med_his"`
-	PreventativeMeasure PreventativeMedicine `json:"preventative_measure"`
-	FamilyHist          []FamilyHistory      `json:"family_hist"`
-	SocialHist          SocialHistory        `json:"social_hist"`
-	SymptonReview       ReviewOfSymptoms     `json:"sympton_review"`
+	Medications         []MedicationCard     `json:"medications" bson:"medications"`
+	Allergies           string               `json:"allergies" bson:"allergies"`
+	PastMedHis          PastMedicalHistory   `json:"past_med_his" bson:"past_med_his"`
+	PreventativeMeasure PreventativeMedicine `json:"preventative_measure" bson:"preventative_measure"`
+	FamilyHist          []FamilyHistory      `json:"family_hist" bson:"family_hist"`
+	SocialHist          SocialHistory        `json:"social_hist" bson:"social_hist"`
+	SymptonReview       ReviewOfSymptoms     `json:"sympton_review" bson:"sympton_review"`
 }
@@ -1,12 +1,12 @@
 package scripts
 
 type MedicationCard struct {
-	Name       string `json:"name"`
-	Brand      string `json:"brand"`
-	Generic    string `json:"generic"`
-	Dose       string `json:"dose"`
-	Frequency  string `json:"frequency"`
-	Reason     string `json:"reason"`
-	StartDate  string `json:"startDate"`
-	OtherNotes string `json:"otherNotes"`
+	Name       string `json:"name" bson:"name"`
+	Brand      string `json:"brand" bson:"brand"`
+	Generic    string `json:"generic" bson:"generic"`
+	Dose       string `json:"dose" bson:"dose"`
+	Frequency  string `json:"frequency" bson:"frequency"`
+	Reason     string `json:"reason" bson:"reason"`
+	StartDate  string `json:"startDate" bson:"startDate"`
+	OtherNotes string `json:"otherNotes" bson:"otherNotes"`
 }
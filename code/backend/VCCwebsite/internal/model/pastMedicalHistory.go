@@ -1,11 +1,11 @@
 package scripts
 
 type PastMedicalHistory struct {
-	ChildHoodIllness   string `json:"child_hood_illness"`
-	IllnessAndHospital string `json:"illness_and_hospital"`
-	Surgeries          string `json:"surgeries"`
-	ObeAndGye          string `json:"obe_and_gye"`
-	Transfusion        string `json:"transfusion"`
-	Psychiatric        string `json:"psychiatric"`
-	Trauma             string `json:"trauma"`
+	ChildHoodIllness   string `json:"child_hood_illness" bson:"child_hood_illness"`
+	IllnessAndHospital string `json:"illness_and_hospital" bson:"illness_and_hospital"`
+	Surgeries          string `json:"surgeries" bson:"surgeries"`
+	ObeAndGye          string `json:"obe_and_gye" bson:"obe_and_gye"`
+	Transfusion        string `json:"transfusion" bson:"transfusion"`
+	Psychiatric        string `json:"psychiatric" bson:"psychiatric"`
+	Trauma             string `json:"trauma" bson:"trauma"`
 }
@@ -1,10 +1,12 @@
 package scripts
 
 type PatientDetails struct {
-	Name              string     `json:"name"`
-	Vitals            VitalSigns `json:"vitals"`
-	VisitReason       string     `json:"visit_reason"`
-	Context           string     `json:"context"`
-	Task              string     `json:"task"`
-	EncounterDuration string     `json:"encounter_duration"`
+	Name              string        `json:"name" bson:"name"`
+	Age               *uint8        `json:"age,omitempty" bson:"age,omitempty"` // nil when unknown
+	Vitals            VitalSigns    `json:"vitals" bson:"vitals"`
+	VitalsTimeline    []VitalsPhase `json:"vitals_timeline,omitempty" bson:"vitals_timeline,omitempty"`
+	VisitReason       string        `json:"visit_reason" bson:"visit_reason"`
+	Context           string        `json:"context" bson:"context"`
+	Task              string        `json:"task" bson:"task"`
+	EncounterDuration string        `json:"encounter_duration" bson:"encounter_duration"`
 }
@@ -1,19 +1,19 @@
 package scripts
 
 type SymptomMarker struct {
-	X float64 `json:"x"`
-	Y float64 `json:"y"`
+	X float64 `json:"x" bson:"x"`
+	Y float64 `json:"y" bson:"y"`
 }
 
 type PresentIllnessHistory struct {
-	BodyLocation        string          `json:"body_location"`
-	SymptomSettings     string          `json:"symptom_settings"`
-	SymptomTiming       string          `json:"symptom_timing"`
-	AssociatedSymptoms  string          `json:"associated_symptoms"`
-	RadiationOfSymptoms string          `json:"radiation_of_symptoms"`
-	SymptomQuality      string          `json:"symptom_quality"`
-	AlleviatingFactors  string          `json:"alleviating_factors"`
-	AggravatingFactors  string          `json:"aggravating_factors"`
-	Pain                uint8           `json:"pain"`
-	SymptomDiagram      []SymptomMarker `json:"symptom_diagram"`
+	BodyLocation        string          `json:"body_location" bson:"body_location"`
+	SymptomSettings     string          `json:"symptom_settings" bson:"symptom_settings"`
+	SymptomTiming       string          `json:"symptom_timing" bson:"symptom_timing"`
+	AssociatedSymptoms  string          `json:"associated_symptoms" bson:"associated_symptoms"`
+	RadiationOfSymptoms string          `json:"radiation_of_symptoms" bson:"radiation_of_symptoms"`
+	SymptomQuality      string          `json:"symptom_quality" bson:"symptom_quality"`
+	AlleviatingFactors  string          `json:"alleviating_factors" bson:"alleviating_factors"`
+	AggravatingFactors  string          `json:"aggravating_factors" bson:"aggravating_factors"`
+	Pain                uint8           `json:"pain" bson:"pain"`
+	SymptomDiagram      []SymptomMarker `json:"symptom_diagram" bson:"symptom_diagram"`
 }
@@ -1,7 +1,7 @@
 package scripts
 
 type PreventativeMedicine struct {
-	Immunization        string `json:"immunization"`
-	AlternateHealthCare string `json:"alternate_health_care"`
-	TravelExposure      string `json:"travel_exposure"`
+	Immunization        string `json:"immunization" bson:"immunization"`
+	AlternateHealthCare string `json:"alternate_health_care" bson:"alternate_health_care"`
+	TravelExposure      string `json:"travel_exposure" bson:"travel_exposure"`
 }
@@ -0,0 +1,19 @@
+package scripts
+
+// Script request workflow statuses
+const (
+	StatusPending      = "Pending"
+	StatusInReview     = "In Review"
+	StatusNeedsChanges = "Needs Changes"
+	StatusApproved     = "Approved"
+	StatusRejected     = "Rejected"
+	StatusArchived     = "Archived"
+)
+
+type StatusTransition struct {
+	From string `json:"from" bson:"from"`
+	To   string `json:"to" bson:"to"`
+	Note string `json:"note,omitempty" bson:"note,omitempty"`
+	By   string `json:"by,omitempty" bson:"by,omitempty"`
+	At   string `json:"at" bson:"at"`
+}
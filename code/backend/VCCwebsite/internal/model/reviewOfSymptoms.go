@@ -1,17 +1,17 @@
 package scripts
 
 type ReviewOfSymptoms struct {
-	General            string `json:"general"`
-	Skin               string `json:"skin"`
-	HEENT              string `json:"heent"`
-	Neck               string `json:"neck"`
-	Breast             string `json:"breast"`
-	Respiratory        string `json:"respiratory"`
-	Cardiovascular     string `json:"cardiovascular"`
-	Gastrointestinal   string `json:"gastrointestinal"`
-	PeripheralVascular string `json:"peripheral_vascular"`
-	Musculoskeletal    string `json:"musculoskeletal"`
-	Psychiatric        string `json:"psychiatric"`
-	Neurologival       string `json:"neurologival"`
-	Endocine           string `json:"endocine"`
+	General            string `json:"general" bson:"general"`
+	Skin               string `json:"skin" bson:"skin"`
+	HEENT              string `json:"heent" bson:"heent"`
+	Neck               string `json:"neck" bson:"neck"`
+	Breast             string `json:"breast" bson:"breast"`
+	Respiratory        string `json:"respiratory" bson:"respiratory"`
+	Cardiovascular     string `json:"cardiovascular" bson:"cardiovascular"`
+	Gastrointestinal   string `json:"gastrointestinal" bson:"gastrointestinal"`
+	PeripheralVascular string `json:"peripheral_vascular" bson:"peripheral_vascular"`
+	Musculoskeletal    string `json:"musculoskeletal" bson:"musculoskeletal"`
+	Psychiatric        string `json:"psychiatric" bson:"psychiatric"`
+	Neurologival       string `json:"neurologival" bson:"neurologival"`
+	Endocine           string `json:"endocine" bson:"endocine"`
 }
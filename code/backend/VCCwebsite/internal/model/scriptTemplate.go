@@ -1,10 +1,13 @@
 package scripts
 
 type StandardizedScript struct {
-	Admin   AdminDetails        `json:"admin"`
-	Patient PatientDetails      `json:"patient"`
-	SP      SPinfo              `json:"sp"`
-	MedHist MedicalHistory      `json:"med_hist"`
-	Special SpecialInstructions `json:"special"`
-	Artifacts []Artifact        `json:"artifacts,omitempty"`
+	Admin           AdminDetails        `json:"admin" bson:"admin"`
+	Patient         PatientDetails      `json:"patient" bson:"patient"`
+	SP              SPinfo              `json:"sp" bson:"sp"`
+	MedHist         MedicalHistory      `json:"med_hist" bson:"med_hist"`
+	Special         SpecialInstructions `json:"special" bson:"special"`
+	Artifacts       []Artifact          `json:"artifacts,omitempty" bson:"artifacts,omitempty"`
+	SourceRequestID string              `json:"source_request_id,omitempty" bson:"source_request_id,omitempty"`
+	CreatedAt       string              `json:"created_at,omitempty" bson:"created_at,omitempty"`
+	UpdatedAt       string              `json:"updated_at,omitempty" bson:"updated_at,omitempty"`
 }
@@ -1,9 +1,9 @@
 package scripts
 
 type SexualHistory struct {
-	CurrentPartners   uint32 `json:"current_partners"`
-	PastPartners      uint32 `json:"past_partners"`
-	Contraceptives    string `json:"contraceptives"`
-	HIVRiskHistory    string `json:"hiv_risk_history"`
-	SafetyInRelations string `json:"safety_in_relations"`
+	CurrentPartners   uint32 `json:"current_partners" bson:"current_partners"`
+	PastPartners      uint32 `json:"past_partners" bson:"past_partners"`
+	Contraceptives    string `json:"contraceptives" bson:"contraceptives"`
+	HIVRiskHistory    string `json:"hiv_risk_history" bson:"hiv_risk_history"`
+	SafetyInRelations string `json:"safety_in_relations" bson:"safety_in_relations"`
 }
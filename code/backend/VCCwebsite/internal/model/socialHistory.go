@@ -1,11 +1,11 @@
 package scripts
 
 type SocialHistory struct {
-	PersonalBackground     string        `json:"personal_background"`
-	NutrionAndExercise     string        `json:"nutrion_and_exercise"`
-	CommunityAndEmployment string        `json:"community_and_employment"`
-	SafetyMeasure          string        `json:"safety_measure"`
-	LifeStressors          string        `json:"life_stressors"`
-	SubstanceUse           string        `json:"substance_use"`
-	SexHistory             SexualHistory `json:"sex_history"`
+	PersonalBackground     string        `json:"personal_background" bson:"personal_background"`
+	NutrionAndExercise     string        `json:"nutrion_and_exercise" bson:"nutrion_and_exercise"`
+	CommunityAndEmployment string        `json:"community_and_employment" bson:"community_and_employment"`
+	SafetyMeasure          string        `json:"safety_measure" bson:"safety_measure"`
+	LifeStressors          string        `json:"life_stressors" bson:"life_stressors"`
+	SubstanceUse           string        `json:"substance_use" bson:"substance_use"`
+	SexHistory             SexualHistory `json:"sex_history" bson:"sex_history"`
 }
@@ -1,9 +1,9 @@
 package scripts
 
 type SpecialInstructions struct {
-	ProvokingQuestion string `json:"provoking_question"`
-	MustAsk           string `json:"must_ask"`
-	Oppurtunity       string `json:"oppurtunity"`
-	OpeningStatement  string `json:"opening_statement"`
-	FeedBack          string `json:"feed_back"`
+	ProvokingQuestion string `json:"provoking_question" bson:"provoking_question"`
+	MustAsk           string `json:"must_ask" bson:"must_ask"`
+	Oppurtunity       string `json:"oppurtunity" bson:"oppurtunity"`
+	OpeningStatement  string `json:"opening_statement" bson:"opening_statement"`
+	FeedBack          string `json:"feed_back" bson:"feed_back"`
 }
@@ -1,8 +1,8 @@
 package scripts
 
 type SPinfo struct {
-	OpeningStatement  string                `json:"opening_statement"`
-	Attributes        emotions              `json:"attributes"`
-	PhysicalChars     string                `json:"physical_chars"`
-	CurrentIllHistory PresentIllnessHistory `json:"current_ill_history"`
+	OpeningStatement  string                `json:"opening_statement" bson:"opening_statement"`
+	Attributes        emotions              `json:"attributes" bson:"attributes"`
+	PhysicalChars     string                `json:"physical_chars" bson:"physical_chars"`
+	CurrentIllHistory PresentIllnessHistory `json:"current_ill_history" bson:"current_ill_history"`
 }
@@ -1,15 +1,30 @@
 package scripts
 
-import "VCCwebsite/internal/utils"
+import "VCCwebsite/internal/measurement"
 
 type VitalSigns struct {
-	HeartRate    int16                  `json:"heart_rate"`
-	Respirations int16                  `json:"respirations"`
-	Pressure     bloodPressure          `json:"pressure"`
-	BloodOxygen  int16                  `json:"blood_oxygen"`
-	Temp         Measurements.Tempature `json:"temp"`
+	HeartRate    int16                   `json:"heart_rate" bson:"heart_rate"`
+	Respirations int16                   `json:"respirations" bson:"respirations"`
+	Pressure     bloodPressure           `json:"pressure" bson:"pressure"`
+	BloodOxygen  int16                   `json:"blood_oxygen" bson:"blood_oxygen"`
+	Temp         measurement.Temperature `json:"temp" bson:"temp"`
+	Weight       measurement.Weight      `json:"weight" bson:"weight"`
+	Height       measurement.Height      `json:"height" bson:"height"`
+	Glucose      measurement.Glucose     `json:"glucose" bson:"glucose"`
+	Orthostatic  *orthostaticVitals      `json:"orthostatic,omitempty" bson:"orthostatic,omitempty"`
 }
 type bloodPressure struct {
-	Top    int16 `json:"top"`
-	Bottom int16 `json:"bottom"`
+	Top    int16 `json:"top" bson:"top"`
+	Bottom int16 `json:"bottom" bson:"bottom"`
+}
+
+// orthostaticVitals are blood pressure and pulse taken lying, sitting and standing
+type orthostaticVitals struct {
+	Lying    positionalVitals `json:"lying" bson:"lying"`
+	Sitting  positionalVitals `json:"sitting" bson:"sitting"`
+	Standing positionalVitals `json:"standing" bson:"standing"`
+}
+type positionalVitals struct {
+	Pressure  bloodPressure `json:"pressure" bson:"pressure"`
+	HeartRate int16         `json:"heart_rate" bson:"heart_rate"`
 }
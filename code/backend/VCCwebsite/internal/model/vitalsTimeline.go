@@ -0,0 +1,13 @@
+package scripts
+
+// BaselinePhase names the vitals in PatientDetails.Vitals, in effect from minute 0
+const BaselinePhase = "baseline"
+
+// VitalsPhase is the state of the vitals from a point of an evolving simulation case
+// on, e.g. after an intervention or during deterioration
+type VitalsPhase struct {
+	Phase         string     `json:"phase" bson:"phase"`
+	OffsetMinutes int        `json:"offset_minutes" bson:"offset_minutes"`
+	Trigger       string     `json:"trigger,omitempty" bson:"trigger,omitempty"`
+	Vitals        VitalSigns `json:"vitals" bson:"vitals"`
+}
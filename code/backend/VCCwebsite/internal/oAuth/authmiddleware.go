@@ -41,10 +41,11 @@ type JWK struct {
 // Claims represents the JWT claims we care about
 type Claims struct {
 	jwt.RegisteredClaims
-	Scp   string `json:"scp,omitempty"`   // Scopes
-	Sub   string `json:"sub"`             // Subject (user ID)
-	Email string `json:"email,omitempty"` // User email
-	Name  string `json:"name,omitempty"`  // User name
+	Scp    string   `json:"scp,omitempty"`    // Scopes
+	Sub    string   `json:"sub"`              // Subject (user ID)
+	Email  string   `json:"email,omitempty"`  // User email
+	Name   string   `json:"name,omitempty"`   // User name
+	Groups []string `json:"groups,omitempty"` // Okta group memberships
 }
 
 // AuthMiddleware validates Okta JWT tokens
@@ -0,0 +1,69 @@
+package oAuth
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// AnyMethod is the Policy key used when no method-specific rule exists
+const AnyMethod = "*"
+
+// Policy maps HTTP methods to the roles allowed to call them.
+// Methods without an entry fall back to AnyMethod; if neither is present the
+// request is allowed for any authenticated user.
+type Policy map[string][]Role
+
+// ReadOnly allows every role to GET and restricts all other methods to the given roles
+func ReadOnly(writers ...Role) Policy {
+	return Policy{
+		http.MethodGet: AllRoles,
+		AnyMethod:      writers,
+	}
+}
+
+// allowed returns the roles permitted for method, or nil when unrestricted
+func (p Policy) allowed(method string) ([]Role, bool) {
+	if roles, ok := p[method]; ok {
+		return roles, true
+	}
+	if roles, ok := p[AnyMethod]; ok {
+		return roles, true
+	}
+	return nil, false
+}
+
+// Authorize wraps next so that it only runs when the caller holds a role permitted
+// by policy. It must run after Middleware so the claims are on the context.
+func (rr *RoleResolver) Authorize(policy Policy, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := GetClaimsFromContext(r.Context())
+		if !ok {
+			writeAuthError(w, http.StatusUnauthorized, "authentication required")
+			return
+		}
+
+		roles := rr.Resolve(claims)
+		if allowed, restricted := policy.allowed(r.Method); restricted && !HasAnyRole(roles, allowed...) {
+			writeAuthError(w, http.StatusForbidden, "forbidden: requires one of roles "+joinRoles(allowed))
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(WithRoles(r.Context(), roles)))
+	})
+}
+
+func joinRoles(roles []Role) string {
+	names := make([]string, 0, len(roles))
+	for _, role := range roles {
+		names = append(names, string(role))
+	}
+	return strings.Join(names, ", ")
+}
+
+// writeAuthError writes the JSON error shape used by the API handlers
+func writeAuthError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
@@ -0,0 +1,173 @@
+package oAuth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// Role is an application-level role derived from Okta groups or the local role table
+type Role string
+
+const (
+	RoleAdmin         Role = "admin"
+	RoleAuthor        Role = "author"
+	RoleReviewer      Role = "reviewer"
+	RoleSPCoordinator Role = "sp_coordinator"
+	RoleFaculty       Role = "faculty" // read-only faculty, the default for any authenticated user
+)
+
+// AllRoles lists every known role, most privileged first
+var AllRoles = []Role{RoleAdmin, RoleAuthor, RoleReviewer, RoleSPCoordinator, RoleFaculty}
+
+// ParseRole converts a role name into a Role, rejecting unknown names
+func ParseRole(name string) (Role, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, role := range AllRoles {
+		if string(role) == name {
+			return role, nil
+		}
+	}
+	return "", fmt.Errorf("unknown role: %q", name)
+}
+
+type rolesContextKey struct{}
+
+// RoleResolver maps authenticated users to roles
+type RoleResolver struct {
+	groupRoles map[string]Role   // Okta group name -> role
+	userRoles  map[string][]Role // lower-cased email or subject -> roles
+}
+
+// NewRoleResolver creates a resolver from a group mapping and a local role table.
+// Either map may be nil.
+func NewRoleResolver(groupRoles map[string]Role, userRoles map[string][]Role) *RoleResolver {
+	rr := &RoleResolver{
+		groupRoles: map[string]Role{},
+		userRoles:  map[string][]Role{},
+	}
+	for group, role := range groupRoles {
+		rr.groupRoles[group] = role
+	}
+	for user, roles := range userRoles {
+		rr.userRoles[strings.ToLower(user)] = roles
+	}
+	return rr
+}
+
+// NewRoleResolverFromEnv builds a resolver from OKTA_ROLE_GROUPS and LOCAL_ROLE_TABLE.
+//
+// OKTA_ROLE_GROUPS is a comma separated list of group=role pairs, e.g.
+// "VCC-Admins=admin,VCC-Authors=author".
+// LOCAL_ROLE_TABLE is the path to a JSON file mapping emails (or Okta subjects)
+// to role lists, e.g. {"jane@wsu.edu": ["author", "reviewer"]}.
+func NewRoleResolverFromEnv() (*RoleResolver, error) {
+	groupRoles := map[string]Role{}
+	if raw := os.Getenv("OKTA_ROLE_GROUPS"); raw != "" {
+		for _, pair := range strings.Split(raw, ",") {
+			pair = strings.TrimSpace(pair)
+			if pair == "" {
+				continue
+			}
+			group, roleName, found := strings.Cut(pair, "=")
+			if !found {
+				return nil, fmt.Errorf("invalid OKTA_ROLE_GROUPS entry: %q", pair)
+			}
+			role, err := ParseRole(roleName)
+			if err != nil {
+				return nil, err
+			}
+			groupRoles[strings.TrimSpace(group)] = role
+		}
+	}
+
+	userRoles := map[string][]Role{}
+	if path := os.Getenv("LOCAL_ROLE_TABLE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read local role table: %w", err)
+		}
+		var table map[string][]string
+		if err := json.Unmarshal(data, &table); err != nil {
+			return nil, fmt.Errorf("failed to decode local role table: %w", err)
+		}
+		for user, names := range table {
+			for _, name := range names {
+				role, err := ParseRole(name)
+				if err != nil {
+					return nil, err
+				}
+				userRoles[user] = append(userRoles[user], role)
+			}
+		}
+	}
+
+	if len(groupRoles) == 0 && len(userRoles) == 0 {
+		log.Printf("WARNING: neither OKTA_ROLE_GROUPS nor LOCAL_ROLE_TABLE maps any roles; every user is read-only faculty and all writes will be refused")
+	}
+
+	return NewRoleResolver(groupRoles, userRoles), nil
+}
+
+// Resolve returns the roles for the given claims. Users without any mapped
+// group or local entry fall back to read-only faculty.
+func (rr *RoleResolver) Resolve(claims *Claims) []Role {
+	if claims == nil {
+		return nil
+	}
+
+	seen := map[Role]bool{}
+	var roles []Role
+	add := func(role Role) {
+		if !seen[role] {
+			seen[role] = true
+			roles = append(roles, role)
+		}
+	}
+
+	for _, key := range []string{claims.Email, claims.Subject, claims.Sub} {
+		if key == "" {
+			continue
+		}
+		for _, role := range rr.userRoles[strings.ToLower(key)] {
+			add(role)
+		}
+	}
+	for _, group := range claims.Groups {
+		if role, ok := rr.groupRoles[group]; ok {
+			add(role)
+		}
+	}
+
+	if len(roles) == 0 {
+		add(RoleFaculty)
+	}
+	return roles
+}
+
+// WithRoles stores the caller's roles on the context
+func WithRoles(ctx context.Context, roles []Role) context.Context {
+	return context.WithValue(ctx, rolesContextKey{}, roles)
+}
+
+// GetRolesFromContext extracts roles from the request context. ok is false
+// when no role information was attached (authentication disabled).
+func GetRolesFromContext(ctx context.Context) ([]Role, bool) {
+	roles, ok := ctx.Value(rolesContextKey{}).([]Role)
+	return roles, ok
+}
+
+// HasAnyRole reports whether roles contains at least one of allowed
+func HasAnyRole(roles []Role, allowed ...Role) bool {
+	for _, role := range roles {
+		for _, a := range allowed {
+			if role == a {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package thumbnail
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+var (
+	backgroundColor = color.RGBA{0xf1, 0xf3, 0xf5, 0xff}
+	pageColor       = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	borderColor     = color.RGBA{0xad, 0xb5, 0xbd, 0xff}
+	lineColor       = color.RGBA{0xdc, 0xe0, 0xe4, 0xff}
+	labelColor      = color.RGBA{0xc9, 0x2a, 0x2a, 0xff}
+)
+
+// pdfGlyphs spell "PDF" in a 5x7 bitmap font
+var pdfGlyphs = [][7]string{
+	{"####.", "#...#", "#...#", "####.", "#....", "#....", "#...."},
+	{"####.", "#...#", "#...#", "#...#", "#...#", "#...#", "####."},
+	{"#####", "#....", "#....", "####.", "#....", "#....", "#...."},
+}
+
+// pdfPlaceholder draws a size x size stand-in for the first page of a PDF: a sheet
+// with a folded corner, grey text lines and a red "PDF" label. Rendering real pages
+// would need a PDF interpreter, which the standard library does not have.
+func pdfPlaceholder(size int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	fill(img, img.Bounds(), backgroundColor)
+
+	// A4-ish sheet centred with a margin
+	pageH := size * 9 / 10
+	pageW := pageH * 7 / 10
+	page := image.Rect((size-pageW)/2, (size-pageH)/2, (size+pageW)/2, (size+pageH)/2)
+	fill(img, page, borderColor)
+	border := max(1, size/128)
+	fill(img, page.Inset(border), pageColor)
+
+	// Folded top right corner
+	fold := pageW / 5
+	for y := 0; y < fold; y++ {
+		for x := pageW - fold + y; x < pageW; x++ {
+			img.SetRGBA(page.Min.X+x, page.Min.Y+y, backgroundColor)
+		}
+		img.SetRGBA(page.Min.X+pageW-fold+y, page.Min.Y+y, borderColor)
+	}
+	fill(img, image.Rect(page.Max.X-fold, page.Min.Y+fold-border, page.Max.X, page.Min.Y+fold), borderColor)
+	fill(img, image.Rect(page.Max.X-fold, page.Min.Y, page.Max.X-fold+border, page.Min.Y+fold), borderColor)
+
+	// Text lines
+	margin := pageW / 8
+	lineH := max(1, pageH/40)
+	for i, y := 0, page.Min.Y+fold+pageH/12; y+lineH < page.Max.Y-pageH/3; i, y = i+1, y+lineH*3 {
+		end := page.Max.X - margin
+		if i%3 == 2 {
+			end -= pageW / 4
+		}
+		fill(img, image.Rect(page.Min.X+margin, y, end, y+lineH), lineColor)
+	}
+
+	// "PDF" label across the lower third
+	scale := max(1, pageW/28)
+	labelW := (len(pdfGlyphs)*6 + 3) * scale
+	labelH := 11 * scale
+	label := image.Rect(page.Min.X+margin, page.Max.Y-pageH/4-labelH/2, page.Min.X+margin+labelW, page.Max.Y-pageH/4+labelH/2)
+	fill(img, label, labelColor)
+	for i, glyph := range pdfGlyphs {
+		originX := label.Min.X + (2+i*6)*scale
+		originY := label.Min.Y + 2*scale
+		for row, bits := range glyph {
+			for col, bit := range bits {
+				if bit == '#' {
+					x, y := originX+col*scale, originY+row*scale
+					fill(img, image.Rect(x, y, x+scale, y+scale), pageColor)
+				}
+			}
+		}
+	}
+	return img
+}
+
+// imagePlaceholder draws a size x size stand-in for an image that has no thumbnail: a
+// framed picture of a hill under the sun
+func imagePlaceholder(size int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	fill(img, img.Bounds(), backgroundColor)
+
+	// 4:3 frame centred with a margin
+	frameW := size * 9 / 10
+	frameH := frameW * 3 / 4
+	frame := image.Rect((size-frameW)/2, (size-frameH)/2, (size+frameW)/2, (size+frameH)/2)
+	fill(img, frame, borderColor)
+	picture := frame.Inset(max(1, size/64))
+	fill(img, picture, pageColor)
+
+	// Sun in the upper left
+	r := max(1, picture.Dy()/8)
+	cx, cy := picture.Min.X+picture.Dx()/4, picture.Min.Y+picture.Dy()/3
+	for y := cy - r; y <= cy+r; y++ {
+		for x := cx - r; x <= cx+r; x++ {
+			if (x-cx)*(x-cx)+(y-cy)*(y-cy) <= r*r {
+				img.SetRGBA(x, y, borderColor)
+			}
+		}
+	}
+
+	// Hill rising to the right, widening down to the bottom of the frame
+	peakX, peakY := picture.Min.X+picture.Dx()*2/3, picture.Min.Y+picture.Dy()*2/5
+	for y := peakY; y < picture.Max.Y; y++ {
+		spread := (y - peakY) * picture.Dx() / (picture.Max.Y - peakY)
+		fill(img, image.Rect(max(picture.Min.X, peakX-spread), y, min(picture.Max.X, peakX+spread), y+1), lineColor)
+	}
+	return img
+}
+
+func fill(img *image.RGBA, r image.Rectangle, c color.RGBA) {
+	draw.Draw(img, r, &image.Uniform{C: c}, image.Point{}, draw.Src)
+}
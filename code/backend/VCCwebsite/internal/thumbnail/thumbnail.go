@@ -0,0 +1,195 @@
+// Package thumbnail renders small previews of artifacts using only the standard
+// library: PNG and JPEG images are downscaled, PDFs get a generated page placeholder.
+package thumbnail
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"sort"
+	"strings"
+)
+
+// MaxPixels caps the size of images that are decoded, so a small file declaring huge
+// dimensions cannot exhaust memory. A 12 MP phone photo still fits; decoded as RGBA
+// it takes about 50 MB.
+const MaxPixels = 4032 * 3024
+
+var (
+	// ErrUnsupported is returned for content types that have no thumbnail
+	ErrUnsupported = errors.New("no thumbnail for this content type")
+	// ErrTooLarge is returned for images above MaxPixels
+	ErrTooLarge = errors.New("image too large for a thumbnail")
+)
+
+// Supported reports whether Render handles the content type
+func Supported(contentType string) bool {
+	switch mediaType(contentType) {
+	case "image/png", "image/jpeg", "application/pdf":
+		return true
+	}
+	return false
+}
+
+// Thumbnail is a rendered preview
+type Thumbnail struct {
+	Size        int // bounds of the square it fits in
+	Content     []byte
+	ContentType string
+}
+
+// Render returns previews of content fitting in each of the size x size squares, in
+// the order of sizes. The image is decoded once; each preview is scaled from the next
+// larger one. Images are never scaled up. JPEGs stay JPEG; everything else is PNG.
+func Render(contentType string, content []byte, sizes ...int) ([]Thumbnail, error) {
+	for _, size := range sizes {
+		if size < 1 {
+			return nil, fmt.Errorf("invalid thumbnail size %d", size)
+		}
+	}
+
+	switch mediaType(contentType) {
+	case "application/pdf":
+		return placeholders(pdfPlaceholder, sizes)
+	case "image/png", "image/jpeg":
+	default:
+		return nil, ErrUnsupported
+	}
+
+	config, format, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	if config.Width*config.Height > MaxPixels {
+		return nil, ErrTooLarge
+	}
+	img, _, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+
+	// Largest first, so every smaller preview averages far fewer pixels
+	thumbnails := make([]Thumbnail, len(sizes))
+	order := make([]int, len(sizes))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return sizes[order[a]] > sizes[order[b]] })
+
+	source := img
+	for _, i := range order {
+		scaled := Scale(source, sizes[i])
+		source = scaled
+
+		thumbnails[i] = Thumbnail{Size: sizes[i]}
+		if format == "jpeg" {
+			var buf bytes.Buffer
+			if err := jpeg.Encode(&buf, scaled, &jpeg.Options{Quality: 80}); err != nil {
+				return nil, err
+			}
+			thumbnails[i].Content, thumbnails[i].ContentType = buf.Bytes(), "image/jpeg"
+			continue
+		}
+		if thumbnails[i].Content, thumbnails[i].ContentType, err = encodePNG(scaled); err != nil {
+			return nil, err
+		}
+	}
+	return thumbnails, nil
+}
+
+// Placeholders returns a generic picture in each of the sizes, in their order, for an
+// image Render failed on (above MaxPixels, or not decodable)
+func Placeholders(sizes ...int) ([]Thumbnail, error) {
+	for _, size := range sizes {
+		if size < 1 {
+			return nil, fmt.Errorf("invalid thumbnail size %d", size)
+		}
+	}
+	return placeholders(imagePlaceholder, sizes)
+}
+
+func placeholders(draw func(size int) *image.RGBA, sizes []int) ([]Thumbnail, error) {
+	thumbnails := make([]Thumbnail, len(sizes))
+	for i, size := range sizes {
+		rendered, renderedType, err := encodePNG(draw(size))
+		if err != nil {
+			return nil, err
+		}
+		thumbnails[i] = Thumbnail{Size: size, Content: rendered, ContentType: renderedType}
+	}
+	return thumbnails, nil
+}
+
+// Scale shrinks img to fit in a size x size square, keeping its aspect ratio, by
+// averaging the source pixels behind each target pixel. Images that already fit are
+// copied unchanged.
+func Scale(img image.Image, size int) *image.RGBA {
+	bounds := img.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	dstW, dstH := srcW, srcH
+	if srcW > size || srcH > size {
+		if srcW >= srcH {
+			dstW, dstH = size, max(1, srcH*size/srcW)
+		} else {
+			dstW, dstH = max(1, srcW*size/srcH), size
+		}
+	}
+
+	at := func(x, y int) (r, g, b, a uint32) { return img.At(x, y).RGBA() }
+	if fast, ok := img.(image.RGBA64Image); ok {
+		at = func(x, y int) (r, g, b, a uint32) {
+			c := fast.RGBA64At(x, y)
+			return uint32(c.R), uint32(c.G), uint32(c.B), uint32(c.A)
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	for dy := 0; dy < dstH; dy++ {
+		y0 := bounds.Min.Y + dy*srcH/dstH
+		y1 := max(y0+1, bounds.Min.Y+(dy+1)*srcH/dstH)
+		for dx := 0; dx < dstW; dx++ {
+			x0 := bounds.Min.X + dx*srcW/dstW
+			x1 := max(x0+1, bounds.Min.X+(dx+1)*srcW/dstW)
+
+			var sumR, sumG, sumB, sumA, n uint64
+			for y := y0; y < y1; y++ {
+				for x := x0; x < x1; x++ {
+					r, g, b, a := at(x, y)
+					sumR += uint64(r)
+					sumG += uint64(g)
+					sumB += uint64(b)
+					sumA += uint64(a)
+					n++
+				}
+			}
+			// Premultiplied 16-bit averages down to 8 bits
+			dst.SetRGBA(dx, dy, color.RGBA{
+				R: uint8(sumR / n >> 8),
+				G: uint8(sumG / n >> 8),
+				B: uint8(sumB / n >> 8),
+				A: uint8(sumA / n >> 8),
+			})
+		}
+	}
+	return dst
+}
+
+func encodePNG(img image.Image) ([]byte, string, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), "image/png", nil
+}
+
+// mediaType strips parameters such as "; charset=utf-8"
+func mediaType(contentType string) string {
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+	return strings.ToLower(strings.TrimSpace(contentType))
+}
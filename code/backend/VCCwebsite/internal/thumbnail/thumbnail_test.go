@@ -0,0 +1,188 @@
+package thumbnail
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// encoded returns a w x h image in format ("png" or "jpeg")
+func encoded(t *testing.T, format string, w, h int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetRGBA(x, y, color.RGBA{uint8(x), uint8(y), 0x80, 0xff})
+		}
+	}
+	var buf bytes.Buffer
+	var err error
+	if format == "jpeg" {
+		err = jpeg.Encode(&buf, img, nil)
+	} else {
+		err = png.Encode(&buf, img)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// pngHeader is the start of a PNG declaring w x h pixels, enough for DecodeConfig
+func pngHeader(w, h uint32) []byte {
+	ihdr := make([]byte, 17)
+	copy(ihdr, "IHDR")
+	binary.BigEndian.PutUint32(ihdr[4:], w)
+	binary.BigEndian.PutUint32(ihdr[8:], h)
+	ihdr[12], ihdr[13] = 8, 6 // 8-bit RGBA
+
+	header := []byte("\x89PNG\r\n\x1a\n")
+	header = binary.BigEndian.AppendUint32(header, 13)
+	header = append(header, ihdr...)
+	return binary.BigEndian.AppendUint32(header, crc32.ChecksumIEEE(ihdr))
+}
+
+func bounds(t *testing.T, thumb Thumbnail) image.Point {
+	t.Helper()
+	config, _, err := image.DecodeConfig(bytes.NewReader(thumb.Content))
+	if err != nil {
+		t.Fatalf("thumbnail %d: %v", thumb.Size, err)
+	}
+	return image.Pt(config.Width, config.Height)
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		format      string
+		w, h        int
+		sizes       []int
+		want        []image.Point
+	}{
+		{"landscape keeps aspect", "image/jpeg", "jpeg", 400, 200, []int{128}, []image.Point{{128, 64}}},
+		{"portrait keeps aspect", "image/png", "png", 100, 300, []int{64}, []image.Point{{21, 64}}},
+		{"never scaled up", "image/png", "png", 100, 50, []int{512}, []image.Point{{100, 50}}},
+		{"thin edge stays a pixel", "image/png", "png", 1, 500, []int{128}, []image.Point{{1, 128}}},
+		{"sizes in request order", "image/jpeg", "jpeg", 600, 300, []int{128, 512}, []image.Point{{128, 64}, {512, 256}}},
+		{"largest first internally", "image/png", "png", 600, 300, []int{512, 64, 128}, []image.Point{{512, 256}, {64, 32}, {128, 64}}},
+		{"content type parameters ignored", "IMAGE/PNG; q=1", "png", 300, 300, []int{100}, []image.Point{{100, 100}}},
+	}
+	for _, tt := range tests {
+		thumbs, err := Render(tt.contentType, encoded(t, tt.format, tt.w, tt.h), tt.sizes...)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if len(thumbs) != len(tt.want) {
+			t.Errorf("%s: got %d thumbnails, want %d", tt.name, len(thumbs), len(tt.want))
+			continue
+		}
+		for i, thumb := range thumbs {
+			if thumb.Size != tt.sizes[i] {
+				t.Errorf("%s: thumbnail %d has size %d, want %d", tt.name, i, thumb.Size, tt.sizes[i])
+			}
+			if got := bounds(t, thumb); got != tt.want[i] {
+				t.Errorf("%s: thumbnail %d is %v, want %v", tt.name, thumb.Size, got, tt.want[i])
+			}
+			if want := "image/" + tt.format; thumb.ContentType != want {
+				t.Errorf("%s: content type %q, want %q", tt.name, thumb.ContentType, want)
+			}
+		}
+	}
+}
+
+func TestRenderMaxPixels(t *testing.T) {
+	tests := []struct {
+		name   string
+		w, h   uint32
+		tooBig bool
+	}{
+		{"12 MP photo", 4032, 3024, false},
+		{"12 MP portrait", 3024, 4032, false},
+		{"one row more", 4032, 3025, true},
+		{"panorama", 20000, 1000, true},
+	}
+	for _, tt := range tests {
+		// Only the header is there, so images under the cap fail to decode instead
+		_, err := Render("image/png", pngHeader(tt.w, tt.h), 128)
+		if (err == ErrTooLarge) != tt.tooBig {
+			t.Errorf("%s: err = %v, too large = %v", tt.name, err, tt.tooBig)
+		}
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	if _, err := Render("image/gif", nil, 128); err != ErrUnsupported {
+		t.Errorf("gif: err = %v, want ErrUnsupported", err)
+	}
+	if _, err := Render("image/png", encoded(t, "png", 10, 10), 128, 0); err == nil {
+		t.Error("size 0: no error")
+	}
+	if _, err := Render("image/jpeg", []byte("not a jpeg"), 128); err == nil {
+		t.Error("corrupt image: no error")
+	}
+}
+
+func TestPlaceholders(t *testing.T) {
+	pdf, err := Render("application/pdf", nil, 128, 512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	generic, err := Placeholders(64, 128)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Placeholders(-1); err == nil {
+		t.Error("size -1: no error")
+	}
+
+	for _, thumb := range append(pdf, generic...) {
+		if thumb.ContentType != "image/png" {
+			t.Errorf("placeholder %d: content type %q", thumb.Size, thumb.ContentType)
+		}
+		if got := bounds(t, thumb); got != image.Pt(thumb.Size, thumb.Size) {
+			t.Errorf("placeholder %d is %v", thumb.Size, got)
+		}
+	}
+	if bytes.Equal(pdf[0].Content, generic[1].Content) {
+		t.Error("PDF and image placeholders are the same picture")
+	}
+}
+
+func TestScale(t *testing.T) {
+	// Black and white columns average to grey
+	img := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 4; x++ {
+			if x%2 == 0 {
+				img.SetRGBA(x, y, color.RGBA{0xff, 0xff, 0xff, 0xff})
+			} else {
+				img.SetRGBA(x, y, color.RGBA{0, 0, 0, 0xff})
+			}
+		}
+	}
+	scaled := Scale(img, 2)
+	if scaled.Bounds() != image.Rect(0, 0, 2, 1) {
+		t.Fatalf("scaled to %v, want 2x1", scaled.Bounds())
+	}
+	for x := 0; x < 2; x++ {
+		if c := scaled.RGBAAt(x, 0); c.R != 0x7f || c.A != 0xff {
+			t.Errorf("pixel %d = %v, want grey", x, c)
+		}
+	}
+
+	// Offset bounds are handled and small images are copied unchanged
+	sub := img.SubImage(image.Rect(1, 0, 3, 2)).(*image.RGBA)
+	if got := Scale(sub, 8).Bounds(); got != image.Rect(0, 0, 2, 2) {
+		t.Errorf("sub-image scaled to %v, want 2x2", got)
+	}
+	if c := Scale(sub, 8).RGBAAt(0, 0); c.R != 0 {
+		t.Errorf("copied pixel = %v, want black", c)
+	}
+}
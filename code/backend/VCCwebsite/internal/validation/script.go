@@ -0,0 +1,73 @@
+package validation
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"VCCwebsite/internal/model"
+)
+
+// Script validates a complete StandardizedScript. The returned error is an Errors
+// value listing every problem, or nil.
+func Script(s *scripts.StandardizedScript) error {
+	c := &checker{}
+	checkScript(c, s, true)
+	return c.result()
+}
+
+// ScriptRequest validates a ScriptRequest, including the ranges of its draft script.
+// Draft scripts are work in progress, so their required fields are not enforced.
+func ScriptRequest(r *scripts.ScriptRequest) error {
+	c := &checker{}
+	c.required("chief_concern", r.ChiefConcern)
+	c.required("learner_level", r.LearnerLevel)
+	c.oneOf("learner_level", r.LearnerLevel, LearnerLevels)
+
+	if r.DraftScript != nil {
+		draft := &checker{prefix: "draft_script"}
+		checkScript(draft, r.DraftScript, false)
+		c.errs = append(c.errs, draft.errs...)
+	}
+	return c.result()
+}
+
+func checkScript(c *checker, s *scripts.StandardizedScript, requireAdmin bool) {
+	admin := s.Admin
+	if requireAdmin {
+		c.required("admin.reson_for_visit", admin.ResonForVisit)
+		c.required("admin.chief_concern", admin.ChiefConcern)
+		c.required("admin.diagnosis", admin.Diagnosis)
+		c.required("admin.learner_level", admin.LearnerLevel)
+		c.required("admin.author", admin.Author)
+	}
+	c.oneOf("admin.learner_level", admin.LearnerLevel, LearnerLevels)
+
+	c.atMost("sp.current_ill_history.pain", int(s.SP.CurrentIllHistory.Pain), MaxPain)
+	for i, marker := range s.SP.CurrentIllHistory.SymptomDiagram {
+		c.unitInterval(fmt.Sprintf("sp.current_ill_history.symptom_diagram[%d].x", i), marker.X)
+		c.unitInterval(fmt.Sprintf("sp.current_ill_history.symptom_diagram[%d].y", i), marker.Y)
+	}
+	checkEmotions(c, "sp.attributes", s.SP.Attributes)
+
+	// Out-of-range vitals are only warnings; impossible ones block the save
+	checkTimeline(c, s.Patient.VitalsTimeline)
+	c.errs = append(c.errs, ScriptVitals(s).Errors...)
+
+	for i, med := range s.MedHist.Medications {
+		if strings.TrimSpace(med.Name) == "" && strings.TrimSpace(med.Brand) == "" && strings.TrimSpace(med.Generic) == "" {
+			c.add(fmt.Sprintf("med_hist.medications[%d].name", i), "is required")
+		}
+	}
+}
+
+// checkEmotions checks every emotion rating against the fixed scale. The emotion
+// struct is unexported in the model, so its fields are walked by their json tags.
+func checkEmotions(c *checker, prefix string, attributes interface{}) {
+	v := reflect.ValueOf(attributes)
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		c.atMost(prefix+"."+name, int(v.Field(i).Uint()), MaxEmotionRating)
+	}
+}
@@ -0,0 +1,112 @@
+// Package validation checks scripts and script requests before they are stored.
+package validation
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// FieldError is a problem with one field, addressed by its JSON path
+type FieldError struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// Errors is the list of problems found in a document
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, fe := range e {
+		msgs = append(msgs, fe.Path+": "+fe.Message)
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Fixed scales used by the script forms
+const (
+	MaxPain          = 10
+	MaxEmotionRating = 5 // None, Mild, Moderate, Concerning, Severe, Extreme
+)
+
+// DefaultLearnerLevels are the learner levels accepted unless LEARNER_LEVELS says otherwise
+var DefaultLearnerLevels = []string{
+	"MS1", "MS2", "MS3", "MS4",
+	"Resident", "Fellow",
+	"PA", "NP", "Nursing", "Pharmacy",
+	"Interprofessional",
+}
+
+// LearnerLevels are the accepted values for learner_level
+var LearnerLevels = DefaultLearnerLevels
+
+// LearnerLevelsFromEnv reads LEARNER_LEVELS, a comma separated list of learner levels
+// replacing the defaults, e.g. "MS1,MS2,MS3,MS4,Resident". It returns
+// DefaultLearnerLevels when the variable is unset or empty.
+func LearnerLevelsFromEnv() []string {
+	var levels []string
+	for _, level := range strings.Split(os.Getenv("LEARNER_LEVELS"), ",") {
+		if level = strings.TrimSpace(level); level != "" {
+			levels = append(levels, level)
+		}
+	}
+	if len(levels) == 0 {
+		return DefaultLearnerLevels
+	}
+	return levels
+}
+
+// checker collects errors under a path prefix
+type checker struct {
+	prefix string
+	errs   Errors
+}
+
+func (c *checker) path(field string) string {
+	if c.prefix == "" {
+		return field
+	}
+	return c.prefix + "." + field
+}
+
+func (c *checker) add(field, format string, args ...interface{}) {
+	c.errs = append(c.errs, FieldError{Path: c.path(field), Message: fmt.Sprintf(format, args...)})
+}
+
+func (c *checker) required(field, value string) {
+	if strings.TrimSpace(value) == "" {
+		c.add(field, "is required")
+	}
+}
+
+func (c *checker) oneOf(field, value string, allowed []string) {
+	if value == "" || len(allowed) == 0 {
+		return
+	}
+	for _, a := range allowed {
+		if strings.EqualFold(a, value) {
+			return
+		}
+	}
+	c.add(field, "must be one of %s", strings.Join(allowed, ", "))
+}
+
+func (c *checker) atMost(field string, value, max int) {
+	if value > max {
+		c.add(field, "must be between 0 and %d", max)
+	}
+}
+
+func (c *checker) unitInterval(field string, value float64) {
+	if value < 0 || value > 1 {
+		c.add(field, "must be between 0 and 1")
+	}
+}
+
+func (c *checker) result() error {
+	if len(c.errs) == 0 {
+		return nil
+	}
+	return c.errs
+}
@@ -0,0 +1,224 @@
+package validation
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"VCCwebsite/internal/measurement"
+	"VCCwebsite/internal/model"
+)
+
+func age(years uint8) *uint8 { return &years }
+
+// validScript passes Script with no errors
+func validScript() scripts.StandardizedScript {
+	var s scripts.StandardizedScript
+	s.Admin.ResonForVisit = "Chest pain"
+	s.Admin.ChiefConcern = "Pressure in the chest"
+	s.Admin.Diagnosis = "Stable angina"
+	s.Admin.LearnerLevel = "MS3"
+	s.Admin.Author = "author@example.edu"
+	s.Patient.Age = age(58)
+	s.Patient.Vitals.HeartRate = 88
+	s.Patient.Vitals.Pressure.Top = 135
+	s.Patient.Vitals.Pressure.Bottom = 85
+	s.SP.CurrentIllHistory.Pain = 6
+	s.SP.CurrentIllHistory.SymptomDiagram = []scripts.SymptomMarker{{X: 0.5, Y: 0.3}}
+	s.SP.Attributes.Anxiety = MaxEmotionRating
+	s.MedHist.Medications = []scripts.MedicationCard{{Name: "Atorvastatin"}}
+	return s
+}
+
+// errorPaths lists the paths of the errors of a validation, in order
+func errorPaths(t *testing.T, err error) []string {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	var fieldErrs Errors
+	if !errors.As(err, &fieldErrs) {
+		t.Fatalf("error %v is not an Errors list", err)
+	}
+	paths := make([]string, 0, len(fieldErrs))
+	for _, fe := range fieldErrs {
+		paths = append(paths, fe.Path)
+	}
+	return paths
+}
+
+func TestScript(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(s *scripts.StandardizedScript)
+		want   []string
+	}{
+		{"valid", func(s *scripts.StandardizedScript) {}, nil},
+		{"admin fields required", func(s *scripts.StandardizedScript) {
+			s.Admin = scripts.AdminDetails{}
+		}, []string{"admin.reson_for_visit", "admin.chief_concern", "admin.diagnosis", "admin.learner_level", "admin.author"}},
+		{"blank counts as missing", func(s *scripts.StandardizedScript) { s.Admin.Author = "  " }, []string{"admin.author"}},
+		{"learner level ignores case", func(s *scripts.StandardizedScript) { s.Admin.LearnerLevel = "resident" }, nil},
+		{"unknown learner level", func(s *scripts.StandardizedScript) { s.Admin.LearnerLevel = "Attending" }, []string{"admin.learner_level"}},
+		{"pain at the top of the scale", func(s *scripts.StandardizedScript) { s.SP.CurrentIllHistory.Pain = MaxPain }, nil},
+		{"pain above the scale", func(s *scripts.StandardizedScript) { s.SP.CurrentIllHistory.Pain = MaxPain + 1 }, []string{"sp.current_ill_history.pain"}},
+		{"markers on the edges", func(s *scripts.StandardizedScript) {
+			s.SP.CurrentIllHistory.SymptomDiagram = []scripts.SymptomMarker{{X: 0, Y: 1}, {X: 1, Y: 0}}
+		}, nil},
+		{"markers outside the diagram", func(s *scripts.StandardizedScript) {
+			s.SP.CurrentIllHistory.SymptomDiagram = []scripts.SymptomMarker{{X: -0.1, Y: 0.5}, {X: 0.5, Y: 0.5}, {X: 0.2, Y: 1.01}}
+		}, []string{"sp.current_ill_history.symptom_diagram[0].x", "sp.current_ill_history.symptom_diagram[2].y"}},
+		{"emotion above the scale", func(s *scripts.StandardizedScript) {
+			s.SP.Attributes.Fear = MaxEmotionRating + 1
+		}, []string{"sp.attributes.fear"}},
+		{"medication needs a name", func(s *scripts.StandardizedScript) {
+			s.MedHist.Medications = append(s.MedHist.Medications, scripts.MedicationCard{Dose: "10 mg"}, scripts.MedicationCard{Brand: "Lipitor"})
+		}, []string{"med_hist.medications[1].name"}},
+		{"impossible vitals", func(s *scripts.StandardizedScript) {
+			s.Patient.Vitals.HeartRate = MaxHeartRate + 1
+			s.Patient.Vitals.Pressure.Bottom = 140
+		}, []string{"patient.vitals.heart_rate", "patient.vitals.pressure.bottom"}},
+		{"abnormal vitals only warn", func(s *scripts.StandardizedScript) { s.Patient.Vitals.HeartRate = 130 }, nil},
+		{"timeline in order", func(s *scripts.StandardizedScript) {
+			s.Patient.VitalsTimeline = []scripts.VitalsPhase{{Phase: "Arrest", OffsetMinutes: 5}, {Phase: "ROSC", OffsetMinutes: 12}}
+		}, nil},
+		{"timeline problems", func(s *scripts.StandardizedScript) {
+			s.Patient.VitalsTimeline = []scripts.VitalsPhase{
+				{Phase: "Baseline", OffsetMinutes: 0},
+				{Phase: "", OffsetMinutes: 10},
+				{Phase: "Later", OffsetMinutes: 10},
+				{Phase: "later", OffsetMinutes: 5},
+			}
+		}, []string{
+			"patient.vitals_timeline[0].phase", "patient.vitals_timeline[0].offset_minutes",
+			"patient.vitals_timeline[1].phase",
+			"patient.vitals_timeline[2].offset_minutes",
+			"patient.vitals_timeline[3].phase", "patient.vitals_timeline[3].offset_minutes",
+		}},
+		{"timeline vitals are checked", func(s *scripts.StandardizedScript) {
+			phase := scripts.VitalsPhase{Phase: "Fever", OffsetMinutes: 20}
+			phase.Vitals.BloodOxygen = 101
+			s.Patient.VitalsTimeline = []scripts.VitalsPhase{phase}
+		}, []string{"patient.vitals_timeline[0].vitals.blood_oxygen"}},
+	}
+	for _, tt := range tests {
+		s := validScript()
+		tt.modify(&s)
+		if got := errorPaths(t, Script(&s)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: errors at %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestScriptRequest(t *testing.T) {
+	draft := validScript()
+	draft.Admin = scripts.AdminDetails{} // drafts need not be complete
+	draft.SP.CurrentIllHistory.Pain = 12
+
+	tests := []struct {
+		name string
+		req  scripts.ScriptRequest
+		want []string
+	}{
+		{"valid", scripts.ScriptRequest{ChiefConcern: "Cough", LearnerLevel: "MS1"}, nil},
+		{"required fields", scripts.ScriptRequest{}, []string{"chief_concern", "learner_level"}},
+		{"unknown learner level", scripts.ScriptRequest{ChiefConcern: "Cough", LearnerLevel: "PGY9"}, []string{"learner_level"}},
+		{"draft ranges checked", scripts.ScriptRequest{ChiefConcern: "Cough", LearnerLevel: "NP", DraftScript: &draft},
+			[]string{"draft_script.sp.current_ill_history.pain"}},
+	}
+	for _, tt := range tests {
+		if got := errorPaths(t, ScriptRequest(&tt.req)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: errors at %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLearnerLevelsFromEnv(t *testing.T) {
+	tests := []struct {
+		env  string
+		want []string
+	}{
+		{"", DefaultLearnerLevels},
+		{" , ", DefaultLearnerLevels},
+		{"PGY1, PGY2 ,,Fellow", []string{"PGY1", "PGY2", "Fellow"}},
+	}
+	for _, tt := range tests {
+		t.Setenv("LEARNER_LEVELS", tt.env)
+		if got := LearnerLevelsFromEnv(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("LEARNER_LEVELS=%q: got %v, want %v", tt.env, got, tt.want)
+		}
+	}
+
+	defer func(levels []string) { LearnerLevels = levels }(LearnerLevels)
+	LearnerLevels = []string{"PGY1"}
+	s := validScript()
+	if got := errorPaths(t, Script(&s)); !reflect.DeepEqual(got, []string{"admin.learner_level"}) {
+		t.Errorf("MS3 with only PGY1 configured: errors at %v", got)
+	}
+}
+
+func TestVitalsAgeBands(t *testing.T) {
+	tests := []struct {
+		name      string
+		age       *uint8
+		heartRate int16
+		breaths   int16
+		warnings  []string
+	}{
+		{"unknown age uses adult ranges", nil, 130, 16, []string{"heart_rate"}},
+		{"newborn", age(0), 140, 36, nil},
+		{"toddler upper edge", age(2), 150, 40, nil},
+		{"preschooler", age(3), 150, 36, []string{"heart_rate", "respirations"}},
+		{"school age", age(11), 120, 30, nil},
+		{"teenager", age(12), 120, 30, []string{"heart_rate", "respirations"}},
+		{"adult", age(40), 72, 14, nil},
+		{"oldest", age(255), 59, 21, []string{"heart_rate", "respirations"}},
+	}
+	for _, tt := range tests {
+		var v scripts.VitalSigns
+		v.HeartRate = tt.heartRate
+		v.Respirations = tt.breaths
+		report := Vitals(v, tt.age)
+		if len(report.Errors) > 0 {
+			t.Errorf("%s: unexpected errors %v", tt.name, report.Errors)
+		}
+		if got := errorPaths(t, errorsOrNil(report.Warnings)); !reflect.DeepEqual(got, tt.warnings) {
+			t.Errorf("%s: warnings at %v, want %v", tt.name, got, tt.warnings)
+		}
+	}
+}
+
+func TestVitalsLimits(t *testing.T) {
+	var v scripts.VitalSigns
+	v.HeartRate = -1
+	v.Temp = measurement.Temperature{Reading: 104, Unit: measurement.Fahrenheit} // 40 °C
+	v.Weight = measurement.Weight{Reading: 1500, Unit: measurement.Pounds}
+	v.Glucose = measurement.Glucose{Reading: 3.5, Unit: measurement.MillimolesPerLiter}
+
+	report := VitalsAt("patient.vitals", v, nil)
+	if got, want := errorPaths(t, errorsOrNil(report.Errors)), []string{"patient.vitals.heart_rate", "patient.vitals.weight.reading"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("errors at %v, want %v", got, want)
+	}
+	if got, want := errorPaths(t, errorsOrNil(report.Warnings)), []string{"patient.vitals.glucose.reading", "patient.vitals.temp.reading"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("warnings at %v, want %v", got, want)
+	}
+
+	v = scripts.VitalSigns{Temp: measurement.Temperature{Reading: 46, Unit: measurement.Celsius}}
+	if got := errorPaths(t, errorsOrNil(Vitals(v, nil).Errors)); !reflect.DeepEqual(got, []string{"temp.reading"}) {
+		t.Errorf("46 °C: errors at %v", got)
+	}
+}
+
+func errorsOrNil(errs Errors) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func TestErrorsMessage(t *testing.T) {
+	err := Errors{{Path: "a", Message: "is required"}, {Path: "b.c", Message: "must be between 0 and 1"}}
+	if got, want := err.Error(), "a: is required; b.c: must be between 0 and 1"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
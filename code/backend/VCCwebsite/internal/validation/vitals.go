@@ -0,0 +1,190 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+
+	"VCCwebsite/internal/measurement"
+	"VCCwebsite/internal/model"
+)
+
+// VitalsReport is the outcome of a plausibility check. Errors are physiologically
+// impossible values; Warnings are values outside the normal range for the patient age.
+type VitalsReport struct {
+	Errors   Errors `json:"errors"`
+	Warnings Errors `json:"warnings"`
+}
+
+// Survivable limits; anything outside them is a typo rather than a case
+const (
+	MaxHeartRate    = 300
+	MaxRespirations = 80
+	MaxSystolic     = 300
+	MaxDiastolic    = 200
+	MaxBloodOxygen  = 100
+	MinTempCelsius  = 25.0
+	MaxTempCelsius  = 45.0
+	MaxWeightKg     = 650.0
+	MaxHeightCm     = 275.0
+	MaxGlucoseMgDl  = 2000.0
+)
+
+// vitalRange is an inclusive normal range
+type vitalRange struct{ min, max float64 }
+
+// ageBand holds the normal resting ranges for patients up to maxAge years old
+type ageBand struct {
+	maxAge       int
+	heartRate    vitalRange
+	respirations vitalRange
+	systolic     vitalRange
+	diastolic    vitalRange
+}
+
+// ageBands follow the usual pediatric reference tables; the last band covers adults
+// and is used when the patient age is unknown. The first band includes infants (age 0).
+var ageBands = []ageBand{
+	{2, vitalRange{90, 150}, vitalRange{24, 40}, vitalRange{80, 110}, vitalRange{45, 70}},
+	{5, vitalRange{80, 140}, vitalRange{22, 34}, vitalRange{80, 110}, vitalRange{50, 75}},
+	{11, vitalRange{70, 120}, vitalRange{18, 30}, vitalRange{90, 120}, vitalRange{55, 80}},
+	{17, vitalRange{60, 100}, vitalRange{12, 20}, vitalRange{90, 130}, vitalRange{60, 85}},
+	{255, vitalRange{60, 100}, vitalRange{12, 20}, vitalRange{90, 140}, vitalRange{60, 90}},
+}
+
+// Normal ranges that do not depend on age
+var (
+	normalBloodOxygen = vitalRange{95, 100}
+	normalTemperature = vitalRange{36.0, 38.0}
+	normalGlucose     = vitalRange{70, 140} // mg/dL, random
+)
+
+func bandFor(age *uint8) ageBand {
+	if age == nil {
+		return ageBands[len(ageBands)-1]
+	}
+	for _, band := range ageBands {
+		if int(*age) <= band.maxAge {
+			return band
+		}
+	}
+	return ageBands[len(ageBands)-1]
+}
+
+// Vitals checks a set of vital signs for a patient of the given age in years, nil when
+// unknown, which applies adult ranges. Zero readings are treated as not entered.
+func Vitals(v scripts.VitalSigns, age *uint8) VitalsReport {
+	return checkVitals("", v, age)
+}
+
+func checkVitals(prefix string, v scripts.VitalSigns, age *uint8) VitalsReport {
+	errs := &checker{prefix: prefix}
+	warns := &checker{prefix: prefix}
+	band := bandFor(age)
+
+	reading := func(field string, value int16, max int, normal vitalRange) {
+		switch {
+		case value == 0:
+		case value < 0:
+			errs.add(field, "cannot be negative")
+		case int(value) > max:
+			errs.add(field, "must be at most %d", max)
+		case float64(value) < normal.min || float64(value) > normal.max:
+			warns.add(field, "%d is outside the normal range %g-%g", value, normal.min, normal.max)
+		}
+	}
+
+	pressure := func(field string, top, bottom int16) {
+		reading(field+".top", top, MaxSystolic, band.systolic)
+		reading(field+".bottom", bottom, MaxDiastolic, band.diastolic)
+		if top > 0 && bottom > 0 && bottom >= top {
+			errs.add(field+".bottom", "diastolic pressure must be below systolic pressure")
+		}
+	}
+
+	reading("heart_rate", v.HeartRate, MaxHeartRate, band.heartRate)
+	reading("respirations", v.Respirations, MaxRespirations, band.respirations)
+	pressure("pressure", v.Pressure.Top, v.Pressure.Bottom)
+	reading("blood_oxygen", v.BloodOxygen, MaxBloodOxygen, normalBloodOxygen)
+
+	if o := v.Orthostatic; o != nil {
+		pressure("orthostatic.lying.pressure", o.Lying.Pressure.Top, o.Lying.Pressure.Bottom)
+		reading("orthostatic.lying.heart_rate", o.Lying.HeartRate, MaxHeartRate, band.heartRate)
+		pressure("orthostatic.sitting.pressure", o.Sitting.Pressure.Top, o.Sitting.Pressure.Bottom)
+		reading("orthostatic.sitting.heart_rate", o.Sitting.HeartRate, MaxHeartRate, band.heartRate)
+		pressure("orthostatic.standing.pressure", o.Standing.Pressure.Top, o.Standing.Pressure.Bottom)
+		reading("orthostatic.standing.heart_rate", o.Standing.HeartRate, MaxHeartRate, band.heartRate)
+	}
+
+	measured := func(field string, value, max float64, unit string, normal *vitalRange) {
+		switch {
+		case value == 0:
+		case value < 0:
+			errs.add(field, "cannot be negative")
+		case value > max:
+			errs.add(field, "must be at most %g %s", max, unit)
+		case normal != nil && (value < normal.min || value > normal.max):
+			warns.add(field, "%.0f %s is outside the normal range %g-%g %s", value, unit, normal.min, normal.max, unit)
+		}
+	}
+	measured("weight.reading", v.Weight.Kilograms(), MaxWeightKg, "kg", nil)
+	measured("height.reading", v.Height.In(measurement.Centimeters).Reading, MaxHeightCm, "cm", nil)
+	measured("glucose.reading", v.Glucose.MgPerDl(), MaxGlucoseMgDl, "mg/dL", &normalGlucose)
+
+	if v.Temp.Reading != 0 {
+		celsius := v.Temp.Celsius()
+		switch {
+		case celsius < MinTempCelsius || celsius > MaxTempCelsius:
+			errs.add("temp.reading", "must be between %g and %g °C", MinTempCelsius, MaxTempCelsius)
+		case celsius < normalTemperature.min || celsius > normalTemperature.max:
+			warns.add("temp.reading", "%.1f °C is outside the normal range %g-%g °C", celsius, normalTemperature.min, normalTemperature.max)
+		}
+	}
+
+	return VitalsReport{Errors: append(Errors{}, errs.errs...), Warnings: append(Errors{}, warns.errs...)}
+}
+
+// VitalsAt checks a set of vital signs like Vitals, reporting paths under prefix
+func VitalsAt(prefix string, v scripts.VitalSigns, age *uint8) VitalsReport {
+	return checkVitals(prefix, v, age)
+}
+
+// ScriptVitals checks the baseline vitals and every phase of the vitals timeline of a
+// script against the patient age
+func ScriptVitals(s *scripts.StandardizedScript) VitalsReport {
+	report := checkVitals("patient.vitals", s.Patient.Vitals, s.Patient.Age)
+	for i, phase := range s.Patient.VitalsTimeline {
+		phaseReport := checkVitals(fmt.Sprintf("patient.vitals_timeline[%d].vitals", i), phase.Vitals, s.Patient.Age)
+		report.Errors = append(report.Errors, phaseReport.Errors...)
+		report.Warnings = append(report.Warnings, phaseReport.Warnings...)
+	}
+	return report
+}
+
+// checkTimeline checks that timeline phases are named uniquely, start after the baseline
+// at minute 0 and are listed in the order they take effect
+func checkTimeline(c *checker, timeline []scripts.VitalsPhase) {
+	seen := map[string]bool{}
+	previous := 0
+	for i, phase := range timeline {
+		field := fmt.Sprintf("patient.vitals_timeline[%d]", i)
+		name := strings.ToLower(strings.TrimSpace(phase.Phase))
+		switch {
+		case name == "":
+			c.add(field+".phase", "is required")
+		case name == scripts.BaselinePhase:
+			c.add(field+".phase", "%q is reserved for patient.vitals", scripts.BaselinePhase)
+		case seen[name]:
+			c.add(field+".phase", "%q is already used by another phase", phase.Phase)
+		}
+		seen[name] = true
+		switch {
+		case phase.OffsetMinutes <= 0:
+			c.add(field+".offset_minutes", "must be greater than 0; minute 0 is the baseline")
+		case phase.OffsetMinutes <= previous:
+			c.add(field+".offset_minutes", "must be greater than the offset of the previous phase (%d)", previous)
+		}
+		if phase.OffsetMinutes > previous {
+			previous = phase.OffsetMinutes
+		}
+	}
+}